@@ -0,0 +1,55 @@
+package main
+
+import "github.com/mark3labs/mcp-go/mcp"
+
+// toolMeta describes how a tool behaves, so clients can decide which calls
+// to confirm with the user
+type toolMeta struct {
+	title string
+	// readOnly tools do not change OmniFocus
+	readOnly bool
+	// destructive tools may change or complete existing items, rather than
+	// only adding new ones
+	destructive bool
+	// idempotent tools have no further effect when repeated with the same
+	// arguments
+	idempotent bool
+}
+
+// toolMetadata holds the annotations for every tool the server registers
+var toolMetadata = map[string]toolMeta{
+	"list_projects":  {title: "List Projects", readOnly: true, idempotent: true},
+	"list_tasks":     {title: "List Tasks", readOnly: true, idempotent: true},
+	"list_tags":      {title: "List Tags", readOnly: true, idempotent: true},
+	"create_task":    {title: "Create Task"},
+	"create_project": {title: "Create Project"},
+	// Completing a repeating task moves it on to its next occurrence, so
+	// repeating a completion completes that one too
+	"update_task":   {title: "Update Task", destructive: true},
+	"complete_task": {title: "Complete Task", destructive: true},
+	"cache_stats":   {title: "Cache Statistics", readOnly: true, idempotent: true},
+	// Clearing the cache leaves OmniFocus alone, but read-only tokens may
+	// not call it, as it makes every caller's next read slow
+	"cache_clear":    {title: "Clear Cache", idempotent: true},
+	"query_database": {title: "Query Database", readOnly: true, idempotent: true},
+}
+
+// newTool creates a tool annotated from toolMetadata. Every tool talks only
+// to the local OmniFocus database, so none is open-world. A tool missing
+// from the table keeps mcp-go's cautious defaults, which treat it as
+// destructive.
+func newTool(name string, opts ...mcp.ToolOption) mcp.Tool {
+	tool := mcp.NewTool(name, opts...)
+	meta, ok := toolMetadata[name]
+	if !ok {
+		return tool
+	}
+	tool.Annotations = mcp.ToolAnnotation{
+		Title:           meta.title,
+		ReadOnlyHint:    mcp.ToBoolPtr(meta.readOnly),
+		DestructiveHint: mcp.ToBoolPtr(meta.destructive),
+		IdempotentHint:  mcp.ToBoolPtr(meta.idempotent),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	}
+	return tool
+}
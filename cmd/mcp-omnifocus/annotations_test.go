@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestToolMetadata_CoversEveryTool(t *testing.T) {
+	s := server.NewMCPServer("test", "0")
+	registerTools(s, &mockClient{})
+	registerCacheTools(s, &mockCacheAdmin{})
+	registerQueryTools(s, &mockQuerier{})
+
+	tools := s.ListTools()
+	for name, tool := range tools {
+		a := tool.Tool.Annotations
+		if _, ok := toolMetadata[name]; !ok {
+			t.Errorf("%s: no entry in toolMetadata", name)
+			continue
+		}
+		if a.Title == "" || a.ReadOnlyHint == nil || a.DestructiveHint == nil || a.IdempotentHint == nil {
+			t.Errorf("%s: expected every annotation to be set, got %+v", name, a)
+		}
+		if *a.ReadOnlyHint && *a.DestructiveHint {
+			t.Errorf("%s: a read-only tool cannot be destructive", name)
+		}
+	}
+	for name := range toolMetadata {
+		if _, ok := tools[name]; !ok {
+			t.Errorf("toolMetadata has an entry for unknown tool %s", name)
+		}
+	}
+}
+
+func TestToolMetadata_Hints(t *testing.T) {
+	s := server.NewMCPServer("test", "0")
+	registerTools(s, &mockClient{})
+
+	tests := []struct {
+		name                              string
+		readOnly, destructive, idempotent bool
+	}{
+		{"list_tasks", true, false, true},
+		{"create_task", false, false, false},
+		{"update_task", false, true, false},
+		{"complete_task", false, true, false},
+	}
+	for _, tt := range tests {
+		a := s.GetTool(tt.name).Tool.Annotations
+		if *a.ReadOnlyHint != tt.readOnly || *a.DestructiveHint != tt.destructive || *a.IdempotentHint != tt.idempotent || *a.OpenWorldHint {
+			t.Errorf("%s: unexpected annotations %+v", tt.name, a)
+		}
+	}
+}
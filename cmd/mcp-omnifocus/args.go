@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+)
+
+// toolArgs decodes tool arguments into typed values. Instead of stopping at
+// the first bad argument it collects a problem for each, so a client can
+// fix them all at once. The expected types are the JSON types declared in
+// the tools' input schemas.
+type toolArgs struct {
+	args     map[string]any
+	problems []string
+}
+
+func newToolArgs(args map[string]any) *toolArgs {
+	return &toolArgs{args: args}
+}
+
+// lookup returns the argument called name. An argument set to null counts
+// as omitted.
+func (a *toolArgs) lookup(name string) (any, bool) {
+	v, ok := a.args[name]
+	return v, ok && v != nil
+}
+
+// null reports whether name was given as an explicit null, which lookup
+// treats as omitted
+func (a *toolArgs) null(name string) bool {
+	v, ok := a.args[name]
+	return ok && v == nil
+}
+
+// invalid records that name does not hold the declared type
+func (a *toolArgs) invalid(name, want string, got any) {
+	a.problems = append(a.problems, fmt.Sprintf("%s must be a %s, got %s", name, want, jsonType(got)))
+}
+
+// requiredString returns a string argument that must be present and not
+// blank
+func (a *toolArgs) requiredString(name string) string {
+	s, ok := a.string(name)
+	if !ok {
+		if _, present := a.lookup(name); !present {
+			a.problems = append(a.problems, name+" is required")
+		}
+		return ""
+	}
+	if strings.TrimSpace(s) == "" {
+		a.problems = append(a.problems, name+" must not be empty")
+	}
+	return s
+}
+
+// string returns an optional string argument
+func (a *toolArgs) string(name string) (string, bool) {
+	v, ok := a.lookup(name)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	if !ok {
+		a.invalid(name, "string", v)
+	}
+	return s, ok
+}
+
+// bool returns an optional boolean argument
+func (a *toolArgs) bool(name string) (bool, bool) {
+	v, ok := a.lookup(name)
+	if !ok {
+		return false, false
+	}
+	b, ok := v.(bool)
+	if !ok {
+		a.invalid(name, "boolean", v)
+	}
+	return b, ok
+}
+
+// minutes returns an optional whole, non-negative number of minutes
+func (a *toolArgs) minutes(name string) (int, bool) {
+	v, ok := a.lookup(name)
+	if !ok {
+		return 0, false
+	}
+	var n float64
+	switch v := v.(type) {
+	case float64:
+		n = v
+	case int:
+		n = float64(v)
+	default:
+		a.invalid(name, "number", v)
+		return 0, false
+	}
+	// The upper bound keeps the conversion to int exact; no task takes a
+	// million minutes
+	if n != math.Trunc(n) || n < 0 || n > 1e6 {
+		a.problems = append(a.problems, fmt.Sprintf("%s must be a whole number of minutes between 0 and 1000000, got %v", name, n))
+		return 0, false
+	}
+	return int(n), true
+}
+
+// err returns the problems found, wrapping ErrInvalidArgument, or nil if
+// every argument was valid
+func (a *toolArgs) err() error {
+	if len(a.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", strings.Join(a.problems, "; "), omnifocus.ErrInvalidArgument)
+}
+
+// jsonType names the JSON type of a decoded argument for error messages
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("string %.40q", v)
+	case float64, int:
+		return fmt.Sprintf("number %v", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// decodeCreateTask decodes the arguments of create_task
+func decodeCreateTask(args map[string]any) (omnifocus.CreateTaskRequest, error) {
+	a := newToolArgs(args)
+	req := omnifocus.CreateTaskRequest{Name: a.requiredString("name")}
+	req.Note, _ = a.string("note")
+	req.ProjectID, _ = a.string("project_id")
+	req.DueDate, _ = a.string("due_date")
+	req.Flagged, _ = a.bool("flagged")
+	req.EstimatedMinutes, _ = a.minutes("estimated_minutes")
+	if tags, ok := a.string("tags"); ok {
+		req.Tags = splitTags(tags)
+	}
+	return req, a.err()
+}
+
+// decodeCreateProject decodes the arguments of create_project
+func decodeCreateProject(args map[string]any) (omnifocus.CreateProjectRequest, error) {
+	a := newToolArgs(args)
+	req := omnifocus.CreateProjectRequest{Name: a.requiredString("name")}
+	req.Note, _ = a.string("note")
+	req.Status, _ = a.string("status")
+	if tags, ok := a.string("tags"); ok {
+		req.Tags = splitTags(tags)
+	}
+	return req, a.err()
+}
+
+// decodeUpdateTask decodes the arguments of update_task. Only the fields
+// given are set, so the others are left unchanged.
+func decodeUpdateTask(args map[string]any) (omnifocus.UpdateTaskRequest, error) {
+	a := newToolArgs(args)
+	req := omnifocus.UpdateTaskRequest{ID: a.requiredString("id")}
+	if name, ok := a.string("name"); ok {
+		req.Name = &name
+	}
+	if note, ok := a.string("note"); ok {
+		req.Note = &note
+	}
+	if completed, ok := a.bool("completed"); ok {
+		req.Completed = &completed
+	}
+	if flagged, ok := a.bool("flagged"); ok {
+		req.Flagged = &flagged
+	}
+	if dueDate, ok := a.string("due_date"); ok {
+		req.DueDate = &dueDate
+	}
+	// A null due date removes it
+	req.ClearDueDate = a.null("due_date")
+	if minutes, ok := a.minutes("estimated_minutes"); ok {
+		req.EstimatedMinutes = &minutes
+	}
+	return req, a.err()
+}
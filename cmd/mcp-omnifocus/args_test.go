@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestDecodeCreateTask_ReportsEveryProblem(t *testing.T) {
+	_, err := decodeCreateTask(map[string]any{
+		"name":              float64(5),
+		"flagged":           "yes",
+		"estimated_minutes": "ten",
+		"tags":              []any{"home"},
+	})
+	if !errors.Is(err, omnifocus.ErrInvalidArgument) {
+		t.Fatalf("expected an invalid argument error, got %v", err)
+	}
+	for _, want := range []string{
+		"name must be a string, got number 5",
+		"flagged must be a boolean, got string \"yes\"",
+		"estimated_minutes must be a number, got string \"ten\"",
+		"tags must be a string, got array",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestDecodeCreateTask_RequiredName(t *testing.T) {
+	for _, args := range []map[string]any{{}, {"name": nil}, {"name": "  "}} {
+		if _, err := decodeCreateTask(args); !errors.Is(err, omnifocus.ErrInvalidArgument) || !strings.Contains(err.Error(), "name") {
+			t.Errorf("%v: expected the name to be required, got %v", args, err)
+		}
+	}
+}
+
+func TestDecodeUpdateTask_NullIsOmitted(t *testing.T) {
+	req, err := decodeUpdateTask(map[string]any{"id": "t1", "name": nil, "flagged": false, "estimated_minutes": nil})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if req.Name != nil || req.EstimatedMinutes != nil || req.Flagged == nil || *req.Flagged {
+		t.Errorf("expected only flagged to be set, got %+v", req)
+	}
+}
+
+func TestDecodeUpdateTask_NullDueDateClears(t *testing.T) {
+	req, err := decodeUpdateTask(map[string]any{"id": "t1", "due_date": nil})
+	if err != nil || !req.ClearDueDate || req.DueDate != nil {
+		t.Errorf("expected a null due date to clear it, got %+v, %v", req, err)
+	}
+
+	req, _ = decodeUpdateTask(map[string]any{"id": "t1"})
+	if req.ClearDueDate {
+		t.Error("expected an omitted due date to leave it alone")
+	}
+	req, _ = decodeUpdateTask(map[string]any{"id": "t1", "due_date": "2025-01-01"})
+	if req.ClearDueDate || req.DueDate == nil || *req.DueDate != "2025-01-01" {
+		t.Errorf("expected the due date to be set, got %+v", req)
+	}
+}
+
+func TestToolArgs_Minutes(t *testing.T) {
+	tests := []struct {
+		value any
+		want  int
+		valid bool
+	}{
+		{float64(30), 30, true},
+		{0, 0, true},
+		{1.5, 0, false},
+		{float64(-10), 0, false},
+		{1e300, 0, false},
+		{true, 0, false},
+	}
+	for _, tt := range tests {
+		a := newToolArgs(map[string]any{"estimated_minutes": tt.value})
+		got, ok := a.minutes("estimated_minutes")
+		if ok != tt.valid || got != tt.want || (a.err() == nil) != tt.valid {
+			t.Errorf("%v: got %d, %v, %v", tt.value, got, ok, a.err())
+		}
+	}
+}
+
+// TestToolArgs_MatchInputSchemas checks that every tool rejects arguments
+// that don't match its input schema, naming the schema's type
+func TestToolArgs_MatchInputSchemas(t *testing.T) {
+	s := server.NewMCPServer("test", "0")
+	registerTools(s, &mockClient{})
+	registerCacheTools(s, &mockCacheAdmin{})
+	registerQueryTools(s, &mockQuerier{result: &omnifocus.QueryResult{}})
+
+	call := func(name string, args map[string]any) string {
+		t.Helper()
+		params, _ := json.Marshal(map[string]any{"name": name, "arguments": args})
+		raw := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":` + string(params) + `}`
+		resp := s.HandleMessage(context.Background(), json.RawMessage(raw)).(mcp.JSONRPCResponse)
+		res := resp.Result.(*mcp.CallToolResult)
+		if !res.IsError {
+			return ""
+		}
+		return extractText(t, res)
+	}
+	wrongValues := map[string]any{"string": true, "boolean": "yes", "number": "ten"}
+
+	for name, tool := range s.ListTools() {
+		schema := tool.Tool.InputSchema
+		msg := call(name, map[string]any{})
+		for _, required := range schema.Required {
+			if !strings.Contains(msg, required+" is required") || !strings.Contains(msg, "INVALID_ARGUMENT") {
+				t.Errorf("%s: expected %s to be required, got %q", name, required, msg)
+			}
+		}
+		for prop, def := range schema.Properties {
+			typ := def.(map[string]any)["type"].(string)
+			msg := call(name, map[string]any{prop: wrongValues[typ]})
+			if want := prop + " must be a " + typ; !strings.Contains(msg, want) {
+				t.Errorf("%s: expected %q, got %q", name, want, msg)
+			}
+		}
+	}
+}
+
+// fuzzArgs decodes fuzzed JSON into tool arguments, skipping inputs that
+// are not a JSON object, as no client can send those
+func fuzzArgs(t *testing.T, data []byte) map[string]any {
+	var args map[string]any
+	if err := json.Unmarshal(data, &args); err != nil {
+		t.Skip()
+	}
+	return args
+}
+
+func FuzzHandleCreateTask(f *testing.F) {
+	f.Add([]byte(`{"name":"Buy milk","note":"2%","project_id":"p1","due_date":"2025-12-31T23:59:59Z","flagged":true,"estimated_minutes":30,"tags":"home, errands"}`))
+	f.Add([]byte(`{"name":5,"flagged":"yes","estimated_minutes":"ten","tags":["home"]}`))
+	f.Add([]byte(`{"name":null,"estimated_minutes":1e308}`))
+	f.Add([]byte(`{}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		args := fuzzArgs(t, data)
+		m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Success: true}}
+		res, err := handleCreateTask(context.Background(), m, args)
+		if err != nil || res == nil {
+			t.Fatalf("expected a result, got %v", err)
+		}
+		if !res.IsError && strings.TrimSpace(m.lastCreateTaskReq.Name) == "" {
+			t.Errorf("created a task without a name from %s", data)
+		}
+	})
+}
+
+func FuzzHandleUpdateTask(f *testing.F) {
+	f.Add([]byte(`{"id":"t1","name":"New","note":"","completed":true,"flagged":false,"due_date":"2025-01-01","estimated_minutes":60}`))
+	f.Add([]byte(`{"id":["t1"],"completed":"true","estimated_minutes":-1}`))
+	f.Add([]byte(`{"id":"t1","due_date":null,"estimated_minutes":0.5}`))
+	f.Add([]byte(`{}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		args := fuzzArgs(t, data)
+		m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Success: true}}
+		res, err := handleUpdateTask(context.Background(), m, args)
+		if err != nil || res == nil {
+			t.Fatalf("expected a result, got %v", err)
+		}
+		if !res.IsError && strings.TrimSpace(m.lastUpdateTaskReq.ID) == "" {
+			t.Errorf("updated a task without an ID from %s", data)
+		}
+	})
+}
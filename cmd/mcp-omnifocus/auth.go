@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"gopkg.in/yaml.v3"
+)
+
+// Token scopes
+const (
+	scopeReadOnly  = "read-only"
+	scopeReadWrite = "read-write"
+)
+
+const (
+	// minTokenLength rejects tokens short enough to guess
+	minTokenLength = 16
+	// tokenCheckInterval is how often the token file is checked for changes
+	tokenCheckInterval = time.Second
+)
+
+// tokenFile is the token file layout
+type tokenFile struct {
+	Tokens []tokenEntry `yaml:"tokens"`
+}
+
+// tokenEntry is one bearer token. Several entries may share a name, so a
+// replacement token can be added before the old one is removed.
+type tokenEntry struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	// Scope is read-only (the default) or read-write
+	Scope string `yaml:"scope"`
+	// Expires, if set, is an RFC 3339 time after which the token is refused
+	Expires string `yaml:"expires"`
+
+	expires time.Time
+}
+
+// principal is the authenticated caller of an HTTP request
+type principal struct {
+	Name     string
+	ReadOnly bool
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalFrom returns the caller of the request ctx belongs to, which is
+// not set for stdio
+func principalFrom(ctx context.Context) (principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(principal)
+	return p, ok
+}
+
+// loadTokens reads and checks a token file. Like an SSH key, it must not be
+// accessible to other users.
+func loadTokens(path string) ([]tokenEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("token file %s must not be accessible to other users (mode %s); run chmod 600", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file tokenFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for i := range file.Tokens {
+		t := &file.Tokens[i]
+		if t.Name == "" {
+			problems = append(problems, fmt.Sprintf("token %d has no name", i+1))
+		}
+		if len(t.Token) < minTokenLength {
+			problems = append(problems, fmt.Sprintf("token %q must be at least %d characters", t.Name, minTokenLength))
+		}
+		if seen[t.Token] {
+			problems = append(problems, fmt.Sprintf("token %q is listed twice", t.Name))
+		}
+		seen[t.Token] = true
+		switch t.Scope {
+		case "":
+			t.Scope = scopeReadOnly
+		case scopeReadOnly, scopeReadWrite:
+		default:
+			problems = append(problems, fmt.Sprintf("token %q has unknown scope %q (valid: %s, %s)", t.Name, t.Scope, scopeReadOnly, scopeReadWrite))
+		}
+		if t.Expires != "" {
+			if t.expires, err = time.Parse(time.RFC3339, t.Expires); err != nil {
+				problems = append(problems, fmt.Sprintf("token %q: invalid expires: %v", t.Name, err))
+			}
+		}
+	}
+	if len(file.Tokens) == 0 {
+		problems = append(problems, "no tokens defined")
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("token file %s: %s", path, strings.Join(problems, "; "))
+	}
+	return file.Tokens, nil
+}
+
+// tokenStore holds the tokens from a token file. The file is read again
+// when it changes, so tokens can be rotated without restarting the server.
+type tokenStore struct {
+	path          string
+	checkInterval time.Duration
+
+	mu      sync.Mutex
+	tokens  []tokenEntry
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+func newTokenStore(path string) (*tokenStore, error) {
+	s := &tokenStore{path: path, checkInterval: tokenCheckInterval}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if s.tokens, err = loadTokens(path); err != nil {
+		return nil, err
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return s, nil
+}
+
+// lookup returns the unexpired entry for token
+func (s *tokenStore) lookup(token string, now time.Time) (tokenEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadIfChanged(now)
+	// Compare digests in constant time so the response time does not
+	// reveal how much of a token matched
+	sum := sha256.Sum256([]byte(token))
+	for _, t := range s.tokens {
+		want := sha256.Sum256([]byte(t.Token))
+		if subtle.ConstantTimeCompare(sum[:], want[:]) == 1 {
+			return t, t.expires.IsZero() || now.Before(t.expires)
+		}
+	}
+	return tokenEntry{}, false
+}
+
+// reloadIfChanged reads the token file again if it has changed since it
+// was last read. An invalid file is logged and the previous tokens kept,
+// so a half-written file does not lock everyone out. s.mu must be held.
+func (s *tokenStore) reloadIfChanged(now time.Time) {
+	if now.Sub(s.checked) < s.checkInterval {
+		return
+	}
+	s.checked = now
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		log.Printf("Failed to check token file, keeping previous tokens: %v", err)
+		return
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return
+	}
+	tokens, err := loadTokens(s.path)
+	if err != nil {
+		log.Printf("Failed to reload tokens, keeping previous tokens: %v", err)
+		return
+	}
+	s.tokens, s.modTime, s.size = tokens, info.ModTime(), info.Size()
+	log.Printf("Reloaded %d tokens from %s", len(tokens), s.path)
+}
+
+// authenticator checks the credentials of HTTP requests: a bearer token
+// from the token store, or a verified client certificate if there is none
+type authenticator struct {
+	tokens *tokenStore
+}
+
+// authenticate identifies the caller of r
+func (a *authenticator) authenticate(r *http.Request) (principal, error) {
+	if a.tokens == nil {
+		// The TLS handshake has already verified the certificate
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return principal{}, errors.New("no client certificate")
+		}
+		return principal{Name: "cert:" + r.TLS.VerifiedChains[0][0].Subject.CommonName}, nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return principal{}, errors.New("no bearer token")
+	}
+	entry, ok := a.tokens.lookup(token, time.Now())
+	if !ok {
+		return principal{}, errors.New("unknown or expired token")
+	}
+	return principal{Name: entry.Name, ReadOnly: entry.Scope != scopeReadWrite}, nil
+}
+
+// middleware rejects unauthenticated requests and records the caller of
+// the others in their context
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticate(r)
+		if err != nil {
+			log.Printf("Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-omnifocus"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
+
+// newAuthenticator returns the authenticator for the configured token file
+func newAuthenticator(cfg Config) (*authenticator, error) {
+	if cfg.TokenFile == "" {
+		return &authenticator{}, nil
+	}
+	tokens, err := newTokenStore(cfg.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	return &authenticator{tokens: tokens}, nil
+}
+
+// tlsConfig returns the TLS settings for the listener, or nil to serve
+// plain HTTP. With a client CA, clients must present a certificate it
+// signed.
+func tlsConfig(cfg Config) (*tls.Config, error) {
+	if cfg.TLSCert == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.TLSClientCA != "" {
+		pem, err := os.ReadFile(cfg.TLSClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", cfg.TLSClientCA)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
+
+// authorizedClient rejects writes from read-only callers and logs which
+// caller made each write. Calls without a principal, over stdio, pass
+// straight through.
+type authorizedClient struct {
+	omnifocus.OmniFocusClient
+}
+
+// authorize checks that the caller in ctx may run action
+func authorize(ctx context.Context, action string) error {
+	p, ok := principalFrom(ctx)
+	if ok && p.ReadOnly {
+		log.Printf("Denied %s to read-only caller %q", action, p.Name)
+		return &omnifocus.Error{
+			Code:      omnifocus.CodeReadOnly,
+			Operation: action,
+			Message:   fmt.Sprintf("token %q may only read", p.Name),
+		}
+	}
+	return nil
+}
+
+// logWrite records which caller ran action on target
+func logWrite(ctx context.Context, action, target string, err error) {
+	p, ok := principalFrom(ctx)
+	if !ok {
+		return
+	}
+	if err != nil {
+		log.Printf("%s %s by %q failed: %v", action, target, p.Name, err)
+		return
+	}
+	log.Printf("%s %s by %q", action, target, p.Name)
+}
+
+// resultID returns the ID in a write result, if there is one
+func resultID(result *omnifocus.OperationResult) string {
+	if result == nil {
+		return ""
+	}
+	return result.ID
+}
+
+func (c authorizedClient) CreateTask(ctx context.Context, req omnifocus.CreateTaskRequest) (*omnifocus.OperationResult, error) {
+	if err := authorize(ctx, "create_task"); err != nil {
+		return nil, err
+	}
+	result, err := c.OmniFocusClient.CreateTask(ctx, req)
+	logWrite(ctx, "create_task", fmt.Sprintf("%q (%s)", req.Name, resultID(result)), err)
+	return result, err
+}
+
+func (c authorizedClient) CreateProject(ctx context.Context, req omnifocus.CreateProjectRequest) (*omnifocus.OperationResult, error) {
+	if err := authorize(ctx, "create_project"); err != nil {
+		return nil, err
+	}
+	result, err := c.OmniFocusClient.CreateProject(ctx, req)
+	logWrite(ctx, "create_project", fmt.Sprintf("%q (%s)", req.Name, resultID(result)), err)
+	return result, err
+}
+
+func (c authorizedClient) UpdateTask(ctx context.Context, req omnifocus.UpdateTaskRequest) (*omnifocus.OperationResult, error) {
+	if err := authorize(ctx, "update_task"); err != nil {
+		return nil, err
+	}
+	result, err := c.OmniFocusClient.UpdateTask(ctx, req)
+	logWrite(ctx, "update_task", req.ID, err)
+	return result, err
+}
+
+func (c authorizedClient) CompleteTask(ctx context.Context, taskID string) (*omnifocus.OperationResult, error) {
+	if err := authorize(ctx, "complete_task"); err != nil {
+		return nil, err
+	}
+	result, err := c.OmniFocusClient.CompleteTask(ctx, taskID)
+	logWrite(ctx, "complete_task", taskID, err)
+	return result, err
+}
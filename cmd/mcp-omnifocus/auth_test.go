@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	readWriteToken = "rw-0123456789abcdef"
+	readOnlyToken  = "ro-0123456789abcdef"
+	testTokens     = `
+tokens:
+  - name: laptop
+    token: ` + readWriteToken + `
+    scope: read-write
+  - name: dashboard
+    token: ` + readOnlyToken + `
+`
+)
+
+// writeTokenFile writes a token file readable only by its owner
+func writeTokenFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokens.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestTokenStore(t *testing.T, content string) *tokenStore {
+	t.Helper()
+	s, err := newTokenStore(writeTokenFile(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// captureLog collects log output for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestLoadTokens(t *testing.T) {
+	tokens, err := loadTokens(writeTokenFile(t, testTokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[0].Scope != scopeReadWrite || tokens[1].Scope != scopeReadOnly {
+		t.Errorf("expected a read-write and a default read-only token, got %+v", tokens)
+	}
+
+	tests := map[string]string{
+		"short":     "tokens:\n  - {name: a, token: short}\n",
+		"scope":     "tokens:\n  - {name: a, token: 0123456789abcdef, scope: admin}\n",
+		"duplicate": "tokens:\n  - {name: a, token: 0123456789abcdef}\n  - {name: b, token: 0123456789abcdef}\n",
+		"no name":   "tokens:\n  - {token: 0123456789abcdef}\n",
+		"expires":   "tokens:\n  - {name: a, token: 0123456789abcdef, expires: tomorrow}\n",
+		"unknown":   "tokens:\n  - {name: a, token: 0123456789abcdef, role: admin}\n",
+		"empty":     "",
+	}
+	for name, content := range tests {
+		if _, err := loadTokens(writeTokenFile(t, content)); err == nil {
+			t.Errorf("%s: expected the token file to be rejected", name)
+		}
+	}
+
+	path := writeTokenFile(t, testTokens)
+	os.Chmod(path, 0o644)
+	if _, err := loadTokens(path); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("expected a readable token file to be rejected, got %v", err)
+	}
+}
+
+func TestTokenStore_Rotation(t *testing.T) {
+	captureLog(t)
+	s := newTestTokenStore(t, testTokens)
+	s.checkInterval = 0
+	now := time.Now()
+
+	if entry, ok := s.lookup(readWriteToken, now); !ok || entry.Name != "laptop" {
+		t.Fatalf("expected the token to be accepted, got %+v, %v", entry, ok)
+	}
+
+	// Rotate the laptop token
+	rotated := "tokens:\n  - {name: laptop, token: rw-fedcba9876543210-new, scope: read-write}\n"
+	if err := os.WriteFile(s.path, []byte(rotated), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.lookup(readWriteToken, now); ok {
+		t.Error("expected the old token to be refused after rotation")
+	}
+	if _, ok := s.lookup("rw-fedcba9876543210-new", now); !ok {
+		t.Error("expected the new token to be accepted")
+	}
+
+	// A broken file keeps the previous tokens
+	os.WriteFile(s.path, []byte("tokens: ["), 0o600)
+	if _, ok := s.lookup("rw-fedcba9876543210-new", now); !ok {
+		t.Error("expected an invalid file to keep the previous tokens")
+	}
+}
+
+func TestTokenStore_Expiry(t *testing.T) {
+	s := newTestTokenStore(t, "tokens:\n  - {name: old, token: 0123456789abcdef, expires: \"2025-01-01T00:00:00Z\"}\n")
+	if _, ok := s.lookup("0123456789abcdef", time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)); !ok {
+		t.Error("expected the token to be accepted before it expires")
+	}
+	if _, ok := s.lookup("0123456789abcdef", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("expected the token to be refused after it expires")
+	}
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	captureLog(t)
+	auth := &authenticator{tokens: newTestTokenStore(t, testTokens)}
+	ts := httptest.NewServer(auth.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := principalFrom(r.Context())
+		io.WriteString(w, p.Name)
+	})))
+	defer ts.Close()
+
+	for header, want := range map[string]string{
+		"":                            "401",
+		"Basic " + readWriteToken:     "401",
+		"Bearer wrong-token-entirely": "401",
+		"Bearer " + readWriteToken:    "laptop",
+		"Bearer " + readOnlyToken:     "dashboard",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		got := string(body)
+		if resp.StatusCode == http.StatusUnauthorized {
+			got = "401"
+			if resp.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("%q: expected a WWW-Authenticate challenge", header)
+			}
+		}
+		if got != want {
+			t.Errorf("%q: expected %s, got %s", header, want, got)
+		}
+	}
+}
+
+func TestAuthorizedClient(t *testing.T) {
+	logs := captureLog(t)
+	m := &mockClient{result: &omnifocus.OperationResult{ID: "t9", Success: true}}
+	c := authorizedClient{m}
+
+	ctx := withPrincipal(context.Background(), principal{Name: "dashboard", ReadOnly: true})
+	if _, err := c.CreateTask(ctx, omnifocus.CreateTaskRequest{Name: "Sneaky"}); !errors.Is(err, omnifocus.ErrReadOnly) {
+		t.Errorf("expected a read-only token to be refused, got %v", err)
+	}
+	if m.lastCreateTaskReq.Name != "" {
+		t.Error("expected the refused write not to reach OmniFocus")
+	}
+	if _, err := c.ListTasks(ctx, ""); err != nil {
+		t.Errorf("expected reads to be allowed, got %v", err)
+	}
+
+	ctx = withPrincipal(context.Background(), principal{Name: "laptop"})
+	if _, err := c.CreateTask(ctx, omnifocus.CreateTaskRequest{Name: "Buy milk"}); err != nil {
+		t.Fatalf("expected a read-write token to write, got %v", err)
+	}
+	if !strings.Contains(logs.String(), `create_task "Buy milk" (t9) by "laptop"`) {
+		t.Errorf("expected the write to be logged with its token, got %q", logs.String())
+	}
+
+	// Over stdio there is no principal
+	if _, err := c.CompleteTask(context.Background(), "t1"); err != nil || m.lastCompleteTaskID != "t1" {
+		t.Errorf("expected calls without a principal to pass through, got %v", err)
+	}
+}
+
+func TestHandleCacheClear_NeedsWriteToken(t *testing.T) {
+	logs := captureLog(t)
+	m := &mockCacheAdmin{}
+
+	ctx := withPrincipal(context.Background(), principal{Name: "dashboard", ReadOnly: true})
+	res, _ := handleCacheClear(ctx, m, map[string]any{"prefix": "tasks:"})
+	if !res.IsError || !strings.Contains(extractText(t, res), "READ_ONLY") {
+		t.Errorf("expected a read-only token to be refused, got %q", extractText(t, res))
+	}
+	if m.lastCleared != nil {
+		t.Error("expected the refused clear not to reach the cache")
+	}
+
+	ctx = withPrincipal(context.Background(), principal{Name: "laptop"})
+	if res, _ := handleCacheClear(ctx, m, map[string]any{"prefix": "tasks:"}); res.IsError || m.lastCleared == nil {
+		t.Fatalf("expected a read-write token to clear the cache, got %q", extractText(t, res))
+	}
+	if !strings.Contains(logs.String(), `cache_clear "tasks:" by "laptop"`) {
+		t.Errorf("expected the clear to be logged with its token, got %q", logs.String())
+	}
+}
+
+func TestHTTPAuth_ReadOnlyTokenCannotWrite(t *testing.T) {
+	captureLog(t)
+	s := server.NewMCPServer("test", "0")
+	registerTools(s, authorizedClient{&mockClient{result: &omnifocus.OperationResult{ID: "t1", Success: true}}})
+	srv := &http.Server{}
+	newHTTPTransport(s, transportHTTP, srv)
+	auth := &authenticator{tokens: newTestTokenStore(t, testTokens)}
+	ts := httptest.NewServer(auth.middleware(srv.Handler))
+	defer ts.Close()
+
+	if resp := postJSON(t, ts.URL+httpEndpoint, "", "", initializeRequest); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a request without a token to be refused, got %d", resp.StatusCode)
+	}
+
+	resp := postJSON(t, ts.URL+httpEndpoint, readOnlyToken, "", initializeRequest)
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"complete_task","arguments":{"id":"t1"}}}`
+	resp = postJSON(t, ts.URL+httpEndpoint, readOnlyToken, sessionID, call)
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `token \"dashboard\" may only read (error code: READ_ONLY)`) {
+		t.Errorf("expected a read-only error, got %s", body)
+	}
+}
+
+// issueCert creates a certificate for template signed by parent, or
+// self-signed if parent is nil, returning it with its key
+func issueCert(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// writePEM writes a certificate and key as PEM files, returning their paths
+func writePEM(t *testing.T, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (certPath, keyPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	certPath = filepath.Join(dir, name+".pem")
+	keyPath = filepath.Join(dir, name+"-key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600)
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600)
+	return certPath, keyPath
+}
+
+func TestServeHTTP_MutualTLS(t *testing.T) {
+	captureLog(t)
+	ca, caKey := issueCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+	serverCert, serverKey := issueCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	clientCert, clientKey := issueCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "laptop"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	cfg := defaultConfig()
+	cfg.TLSCert, cfg.TLSKey = writePEM(t, "server", serverCert, serverKey)
+	cfg.TLSClientCA, _ = writePEM(t, "ca", ca, caKey)
+	tlsCfg, err := tlsConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go serveHTTP(ctx, newToolServer(), transportHTTP, tls.NewListener(ln, tlsCfg), &authenticator{})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	url := "https://" + ln.Addr().String() + httpEndpoint
+	post := func(certs []tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		return client.Post(url, "application/json", strings.NewReader(initializeRequest))
+	}
+
+	if resp, err := post(nil); err == nil {
+		resp.Body.Close()
+		t.Error("expected a client without a certificate to be refused")
+	}
+	resp, err := post([]tls.Certificate{{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}})
+	if err != nil {
+		t.Fatalf("expected a client certificate signed by the CA to be accepted, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected initialize to succeed, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,731 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the fully resolved server configuration. Each setting is taken
+// from, in order of precedence: a command line flag, an environment
+// variable, the selected profile, the top level of the config file, and
+// finally the built-in default.
+type Config struct {
+	ScriptsPath string
+	CacheTTL    time.Duration
+	// CacheStaleTTL is how long after CacheTTL a list may still be served
+	// while it is refreshed in the background
+	CacheStaleTTL time.Duration
+	// CacheTTLs overrides CacheTTL for cache keys starting with a prefix,
+	// e.g. "tags:"
+	CacheTTLs map[string]time.Duration
+	// CacheMaxEntries and CacheMaxBytes bound each list cache; zero means
+	// no limit
+	CacheMaxEntries int
+	CacheMaxBytes   int
+	// ChangeDetectionInterval is how often to probe OmniFocus for changes
+	// made outside the server; zero disables probing
+	ChangeDetectionInterval time.Duration
+	// IncrementalSync keeps a local copy of every task, refreshed by
+	// fetching only modified tasks, with a full read at least every
+	// FullSyncInterval
+	IncrementalSync  bool
+	FullSyncInterval time.Duration
+	// Mirror keeps a SQLite copy of the database for the query_database
+	// tool, in MirrorPath or in memory if that is empty, refreshed after
+	// changes and every MirrorRefreshInterval
+	Mirror                bool
+	MirrorPath            string
+	MirrorRefreshInterval time.Duration
+	// DiskCache persists cached lists in DiskCacheDir, or the user cache
+	// directory if that is empty
+	DiskCache    bool
+	DiskCacheDir string
+	TimeZone     string
+	ReadOnly     bool
+	Timeout      time.Duration
+	// OperationTimeouts overrides Timeout per operation, e.g. "list_tasks"
+	OperationTimeouts map[string]time.Duration
+	Retry             omnifocus.RetryPolicy
+	// MaxConcurrentReads and MaxConcurrentWrites bound how many scripts run
+	// against OmniFocus at once
+	MaxConcurrentReads  int
+	MaxConcurrentWrites int
+	Scope               omnifocus.Scope
+	LogFile             string
+	Debug               bool
+	// Transport is how clients connect: stdio, or sse or http on
+	// ListenAddr, which lets several clients share one server and cache
+	Transport  string
+	ListenAddr string
+	// TokenFile lists the bearer tokens accepted by the sse and http
+	// transports. TLSCert and TLSKey serve them over HTTPS; with
+	// TLSClientCA, clients must also present a certificate it signed.
+	TokenFile   string
+	TLSCert     string
+	TLSKey      string
+	TLSClientCA string
+}
+
+// defaultConfig returns the configuration used when nothing is set
+func defaultConfig() Config {
+	return Config{
+		CacheTTL:      30 * time.Second,
+		CacheStaleTTL: 5 * time.Minute,
+		Timeout:       60 * time.Second,
+		Retry:         omnifocus.DefaultRetryPolicy(),
+
+		FullSyncInterval:      10 * time.Minute,
+		MirrorRefreshInterval: 5 * time.Minute,
+
+		MaxConcurrentReads:  omnifocus.DefaultMaxConcurrentReads,
+		MaxConcurrentWrites: omnifocus.DefaultMaxConcurrentWrites,
+
+		Transport:  transportStdio,
+		ListenAddr: defaultListenAddr,
+	}
+}
+
+// fileSettings holds the settings that may appear at the top level of the
+// config file or inside a profile. Pointers distinguish unset from zero.
+type fileSettings struct {
+	Scripts  *string          `yaml:"scripts"`
+	CacheTTL *string          `yaml:"cacheTTL"`
+	TimeZone *string          `yaml:"timeZone"`
+	ReadOnly *bool            `yaml:"readOnly"`
+	Timeout  *string          `yaml:"timeout"`
+	Scope    *omnifocus.Scope `yaml:"scope"`
+	Logging  *fileLogging     `yaml:"logging"`
+
+	CacheStaleTTL     *string           `yaml:"cacheStaleTTL"`
+	OperationTimeouts map[string]string `yaml:"operationTimeouts"`
+	Retry             *fileRetry        `yaml:"retry"`
+	Concurrency       *fileConcurrency  `yaml:"concurrency"`
+	DiskCache         *fileDiskCache    `yaml:"diskCache"`
+	CacheTTLs         map[string]string `yaml:"cacheTTLs"`
+	CacheLimits       *fileCacheLimits  `yaml:"cacheLimits"`
+	ChangeDetection   *fileChangeDetect `yaml:"changeDetection"`
+	IncrementalSync   *fileSync         `yaml:"incrementalSync"`
+	Mirror            *fileMirror       `yaml:"mirror"`
+	Transport         *fileTransport    `yaml:"transport"`
+}
+
+// fileTransport holds the transport section of the config file
+type fileTransport struct {
+	Type      *string  `yaml:"type"`
+	Listen    *string  `yaml:"listen"`
+	TokenFile *string  `yaml:"tokenFile"`
+	TLS       *fileTLS `yaml:"tls"`
+}
+
+// fileTLS holds the transport.tls section of the config file
+type fileTLS struct {
+	Cert     *string `yaml:"cert"`
+	Key      *string `yaml:"key"`
+	ClientCA *string `yaml:"clientCA"`
+}
+
+// fileMirror holds the mirror section of the config file
+type fileMirror struct {
+	Enabled         *bool   `yaml:"enabled"`
+	Path            *string `yaml:"path"`
+	RefreshInterval *string `yaml:"refreshInterval"`
+}
+
+// fileSync holds the incrementalSync section of the config file
+type fileSync struct {
+	Enabled          *bool   `yaml:"enabled"`
+	FullSyncInterval *string `yaml:"fullSyncInterval"`
+}
+
+// fileChangeDetect holds the changeDetection section of the config file
+type fileChangeDetect struct {
+	Interval *string `yaml:"interval"`
+}
+
+// fileCacheLimits holds the cacheLimits section of the config file
+type fileCacheLimits struct {
+	MaxEntries *int `yaml:"maxEntries"`
+	MaxBytes   *int `yaml:"maxBytes"`
+}
+
+// fileDiskCache holds the diskCache section of the config file
+type fileDiskCache struct {
+	Enabled *bool   `yaml:"enabled"`
+	Dir     *string `yaml:"dir"`
+}
+
+// fileRetry holds the retry section of the config file
+type fileRetry struct {
+	MaxAttempts    *int     `yaml:"maxAttempts"`
+	InitialBackoff *string  `yaml:"initialBackoff"`
+	MaxBackoff     *string  `yaml:"maxBackoff"`
+	Jitter         *float64 `yaml:"jitter"`
+}
+
+// fileConcurrency holds the concurrency section of the config file
+type fileConcurrency struct {
+	Reads  *int `yaml:"reads"`
+	Writes *int `yaml:"writes"`
+}
+
+// fileLogging holds the logging section of the config file
+type fileLogging struct {
+	File  *string `yaml:"file"`
+	Debug *bool   `yaml:"debug"`
+}
+
+// configFile is the on-disk config file layout
+type configFile struct {
+	fileSettings `yaml:",inline"`
+	Profiles     map[string]fileSettings `yaml:"profiles"`
+}
+
+// ConfigError reports every problem found while resolving the configuration
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// defaultConfigPath returns ~/.config/mcp-omnifocus/config.yaml, honouring
+// XDG_CONFIG_HOME when it is set
+func defaultConfigPath(getenv func(string) string) string {
+	base := getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "mcp-omnifocus", "config.yaml")
+}
+
+// loadConfigFile reads a YAML or JSON config file. Unknown keys are rejected
+// so typos surface at startup instead of being silently ignored.
+func loadConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg configFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// configFlags holds the command line flags that map onto Config
+type configFlags struct {
+	configPath    *string
+	profile       *string
+	scripts       *string
+	cacheTTL      *ttlValue
+	timeZone      *string
+	readOnly      *bool
+	timeout       *time.Duration
+	allowFolders  *string
+	allowProjects *string
+	allowTags     *string
+	denyFolders   *string
+	denyProjects  *string
+	denyTags      *string
+	transport     *string
+	listen        *string
+	tokenFile     *string
+	tlsCert       *string
+	tlsKey        *string
+	tlsClientCA   *string
+}
+
+// parseTTL parses a cache TTL: a duration such as 30s, or a bare number of
+// seconds, which is how the TTL was first configured
+func parseTTL(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither a duration such as 60s nor a number of seconds", s)
+	}
+	return d, nil
+}
+
+// ttlValue is a flag.Value holding a TTL parsed by parseTTL
+type ttlValue time.Duration
+
+func newTTLFlag(fs *flag.FlagSet, name string, value time.Duration, usage string) *ttlValue {
+	v := ttlValue(value)
+	fs.Var(&v, name, usage)
+	return &v
+}
+
+func (v *ttlValue) String() string { return time.Duration(*v).String() }
+
+func (v *ttlValue) Set(s string) error {
+	d, err := parseTTL(s)
+	if err != nil {
+		return err
+	}
+	*v = ttlValue(d)
+	return nil
+}
+
+// registerConfigFlags defines the configuration flags on fs
+func registerConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		configPath:    fs.String("config", "", "Path to a YAML or JSON config file (default ~/.config/mcp-omnifocus/config.yaml)"),
+		profile:       fs.String("profile", "", "Named profile from the config file to apply"),
+		scripts:       fs.String("scripts", "", "Path to the JXA scripts directory (if not specified, auto-detection is used)"),
+		cacheTTL:      newTTLFlag(fs, "cache-ttl", 30*time.Second, "Cache TTL, e.g. 30s or 5m, or a number of seconds (0 to disable caching)"),
+		timeZone:      fs.String("timezone", "", "IANA time zone for dates without an explicit offset (default: system zone)"),
+		readOnly:      fs.Bool("read-only", false, "Reject all write operations"),
+		timeout:       fs.Duration("timeout", 60*time.Second, "Maximum duration of a single OmniFocus script (0 to disable)"),
+		allowFolders:  fs.String("allow-folders", "", "Comma-separated folders the assistant may access"),
+		allowProjects: fs.String("allow-projects", "", "Comma-separated projects the assistant may access"),
+		allowTags:     fs.String("allow-tags", "", "Comma-separated tags the assistant may access"),
+		denyFolders:   fs.String("deny-folders", "", "Comma-separated folders hidden from the assistant"),
+		denyProjects:  fs.String("deny-projects", "", "Comma-separated projects hidden from the assistant"),
+		denyTags:      fs.String("deny-tags", "", "Comma-separated tags hidden from the assistant"),
+		transport:     fs.String("transport", transportStdio, "Transport: stdio, sse or http"),
+		listen:        fs.String("listen", defaultListenAddr, "Address to listen on for the sse and http transports"),
+		tokenFile:     fs.String("token-file", "", "File of bearer tokens accepted by the sse and http transports"),
+		tlsCert:       fs.String("tls-cert", "", "TLS certificate for the sse and http transports"),
+		tlsKey:        fs.String("tls-key", "", "TLS private key for the sse and http transports"),
+		tlsClientCA:   fs.String("tls-client-ca", "", "CA that must have signed client certificates (enables mutual TLS)"),
+	}
+}
+
+// resolveConfig merges defaults, the config file, environment variables and
+// the flags parsed into fs. All problems are collected into a ConfigError.
+func resolveConfig(fs *flag.FlagSet, flags *configFlags, getenv func(string) string) (Config, error) {
+	cfg := defaultConfig()
+	var problems []string
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// pick returns the flag value if set, otherwise the environment value
+	pick := func(flagName, flagValue, envName string) string {
+		if set[flagName] {
+			return flagValue
+		}
+		return getenv(envName)
+	}
+
+	// Locate the config file; a missing default file is not an error
+	path := pick("config", *flags.configPath, "MCP_OMNIFOCUS_CONFIG")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath(getenv)
+	}
+
+	var file *configFile
+	if path != "" {
+		loaded, err := loadConfigFile(path)
+		switch {
+		case err == nil:
+			file = loaded
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			problems = append(problems, err.Error())
+		}
+	}
+
+	// Apply the top level of the file, then the selected profile
+	if file != nil {
+		problems = append(problems, applyFileSettings(&cfg, file.fileSettings, "")...)
+	}
+	if profile := pick("profile", *flags.profile, "MCP_OMNIFOCUS_PROFILE"); profile != "" {
+		var settings fileSettings
+		var ok bool
+		if file != nil {
+			settings, ok = file.Profiles[profile]
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("profile %q is not defined in the config file", profile))
+		} else {
+			problems = append(problems, applyFileSettings(&cfg, settings, "profiles."+profile+".")...)
+		}
+	}
+
+	// Environment variables override the file
+	if v := getenv("MCP_OMNIFOCUS_SCRIPTS"); v != "" {
+		cfg.ScriptsPath = v
+	}
+	if v := getenv("MCP_OMNIFOCUS_CACHE_TTL"); v != "" {
+		if d, err := parseTTL(v); err != nil {
+			problems = append(problems, fmt.Sprintf("MCP_OMNIFOCUS_CACHE_TTL: %v", err))
+		} else {
+			cfg.CacheTTL = d
+		}
+	}
+	if v := getenv("MCP_OMNIFOCUS_TIMEZONE"); v != "" {
+		cfg.TimeZone = v
+	}
+	if v := getenv("MCP_OMNIFOCUS_READ_ONLY"); v != "" {
+		if b, err := strconv.ParseBool(v); err != nil {
+			problems = append(problems, fmt.Sprintf("MCP_OMNIFOCUS_READ_ONLY: %q is not a boolean", v))
+		} else {
+			cfg.ReadOnly = b
+		}
+	}
+	if v := getenv("MCP_OMNIFOCUS_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil {
+			problems = append(problems, fmt.Sprintf("MCP_OMNIFOCUS_TIMEOUT: %v", err))
+		} else {
+			cfg.Timeout = d
+		}
+	}
+	if v := getenv("MCP_OMNIFOCUS_TRANSPORT"); v != "" {
+		cfg.Transport = v
+	}
+	if v := getenv("MCP_OMNIFOCUS_LISTEN"); v != "" {
+		cfg.ListenAddr = v
+	}
+	if v := getenv("MCP_OMNIFOCUS_TOKEN_FILE"); v != "" {
+		cfg.TokenFile = v
+	}
+	if getenv("MCP_OMNIFOCUS_DEBUG") == "1" {
+		cfg.Debug = true
+	}
+
+	// Flags override everything
+	if set["scripts"] {
+		cfg.ScriptsPath = *flags.scripts
+	}
+	if set["cache-ttl"] {
+		cfg.CacheTTL = time.Duration(*flags.cacheTTL)
+	}
+	if set["timezone"] {
+		cfg.TimeZone = *flags.timeZone
+	}
+	if set["read-only"] {
+		cfg.ReadOnly = *flags.readOnly
+	}
+	if set["timeout"] {
+		cfg.Timeout = *flags.timeout
+	}
+	if set["transport"] {
+		cfg.Transport = *flags.transport
+	}
+	if set["listen"] {
+		cfg.ListenAddr = *flags.listen
+	}
+	if set["token-file"] {
+		cfg.TokenFile = *flags.tokenFile
+	}
+	if set["tls-cert"] {
+		cfg.TLSCert = *flags.tlsCert
+	}
+	if set["tls-key"] {
+		cfg.TLSKey = *flags.tlsKey
+	}
+	if set["tls-client-ca"] {
+		cfg.TLSClientCA = *flags.tlsClientCA
+	}
+	scopeFlags := []struct {
+		name  string
+		value *string
+		dest  *[]string
+	}{
+		{"allow-folders", flags.allowFolders, &cfg.Scope.AllowFolders},
+		{"allow-projects", flags.allowProjects, &cfg.Scope.AllowProjects},
+		{"allow-tags", flags.allowTags, &cfg.Scope.AllowTags},
+		{"deny-folders", flags.denyFolders, &cfg.Scope.DenyFolders},
+		{"deny-projects", flags.denyProjects, &cfg.Scope.DenyProjects},
+		{"deny-tags", flags.denyTags, &cfg.Scope.DenyTags},
+	}
+	for _, sf := range scopeFlags {
+		if set[sf.name] {
+			*sf.dest = splitTags(*sf.value)
+		}
+	}
+
+	problems = append(problems, validateConfig(cfg)...)
+	if len(problems) > 0 {
+		return cfg, &ConfigError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// applyFileSettings copies the settings present in s onto cfg. The prefix
+// qualifies field names in problem reports.
+func applyFileSettings(cfg *Config, s fileSettings, prefix string) []string {
+	var problems []string
+
+	if s.Scripts != nil {
+		cfg.ScriptsPath = *s.Scripts
+	}
+	if s.CacheTTL != nil {
+		if d, err := parseTTL(*s.CacheTTL); err != nil {
+			problems = append(problems, fmt.Sprintf("%scacheTTL: %v", prefix, err))
+		} else {
+			cfg.CacheTTL = d
+		}
+	}
+	if s.CacheStaleTTL != nil {
+		if d, err := time.ParseDuration(*s.CacheStaleTTL); err != nil {
+			problems = append(problems, fmt.Sprintf("%scacheStaleTTL: %v", prefix, err))
+		} else {
+			cfg.CacheStaleTTL = d
+		}
+	}
+	if s.TimeZone != nil {
+		cfg.TimeZone = *s.TimeZone
+	}
+	if s.ReadOnly != nil {
+		cfg.ReadOnly = *s.ReadOnly
+	}
+	if s.Timeout != nil {
+		if d, err := time.ParseDuration(*s.Timeout); err != nil {
+			problems = append(problems, fmt.Sprintf("%stimeout: %v", prefix, err))
+		} else {
+			cfg.Timeout = d
+		}
+	}
+	for op, v := range s.OperationTimeouts {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%soperationTimeouts.%s: %v", prefix, op, err))
+			continue
+		}
+		if cfg.OperationTimeouts == nil {
+			cfg.OperationTimeouts = make(map[string]time.Duration)
+		}
+		cfg.OperationTimeouts[op] = d
+	}
+	if s.Retry != nil {
+		if s.Retry.MaxAttempts != nil {
+			cfg.Retry.MaxAttempts = *s.Retry.MaxAttempts
+		}
+		if s.Retry.InitialBackoff != nil {
+			if d, err := time.ParseDuration(*s.Retry.InitialBackoff); err != nil {
+				problems = append(problems, fmt.Sprintf("%sretry.initialBackoff: %v", prefix, err))
+			} else {
+				cfg.Retry.InitialBackoff = d
+			}
+		}
+		if s.Retry.MaxBackoff != nil {
+			if d, err := time.ParseDuration(*s.Retry.MaxBackoff); err != nil {
+				problems = append(problems, fmt.Sprintf("%sretry.maxBackoff: %v", prefix, err))
+			} else {
+				cfg.Retry.MaxBackoff = d
+			}
+		}
+		if s.Retry.Jitter != nil {
+			cfg.Retry.Jitter = *s.Retry.Jitter
+		}
+	}
+	if s.Concurrency != nil {
+		if s.Concurrency.Reads != nil {
+			cfg.MaxConcurrentReads = *s.Concurrency.Reads
+		}
+		if s.Concurrency.Writes != nil {
+			cfg.MaxConcurrentWrites = *s.Concurrency.Writes
+		}
+	}
+	for keyPrefix, v := range s.CacheTTLs {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%scacheTTLs.%s: %v", prefix, keyPrefix, err))
+			continue
+		}
+		if cfg.CacheTTLs == nil {
+			cfg.CacheTTLs = make(map[string]time.Duration)
+		}
+		cfg.CacheTTLs[keyPrefix] = d
+	}
+	if s.CacheLimits != nil {
+		if s.CacheLimits.MaxEntries != nil {
+			cfg.CacheMaxEntries = *s.CacheLimits.MaxEntries
+		}
+		if s.CacheLimits.MaxBytes != nil {
+			cfg.CacheMaxBytes = *s.CacheLimits.MaxBytes
+		}
+	}
+	if s.ChangeDetection != nil && s.ChangeDetection.Interval != nil {
+		if d, err := time.ParseDuration(*s.ChangeDetection.Interval); err != nil {
+			problems = append(problems, fmt.Sprintf("%schangeDetection.interval: %v", prefix, err))
+		} else {
+			cfg.ChangeDetectionInterval = d
+		}
+	}
+	if s.IncrementalSync != nil {
+		if s.IncrementalSync.Enabled != nil {
+			cfg.IncrementalSync = *s.IncrementalSync.Enabled
+		}
+		if s.IncrementalSync.FullSyncInterval != nil {
+			if d, err := time.ParseDuration(*s.IncrementalSync.FullSyncInterval); err != nil {
+				problems = append(problems, fmt.Sprintf("%sincrementalSync.fullSyncInterval: %v", prefix, err))
+			} else {
+				cfg.FullSyncInterval = d
+			}
+		}
+	}
+	if s.Mirror != nil {
+		if s.Mirror.Enabled != nil {
+			cfg.Mirror = *s.Mirror.Enabled
+		}
+		if s.Mirror.Path != nil {
+			cfg.MirrorPath = *s.Mirror.Path
+		}
+		if s.Mirror.RefreshInterval != nil {
+			if d, err := time.ParseDuration(*s.Mirror.RefreshInterval); err != nil {
+				problems = append(problems, fmt.Sprintf("%smirror.refreshInterval: %v", prefix, err))
+			} else {
+				cfg.MirrorRefreshInterval = d
+			}
+		}
+	}
+	if s.DiskCache != nil {
+		if s.DiskCache.Enabled != nil {
+			cfg.DiskCache = *s.DiskCache.Enabled
+		}
+		if s.DiskCache.Dir != nil {
+			cfg.DiskCacheDir = *s.DiskCache.Dir
+		}
+	}
+	if s.Scope != nil {
+		cfg.Scope = *s.Scope
+	}
+	if s.Logging != nil {
+		if s.Logging.File != nil {
+			cfg.LogFile = *s.Logging.File
+		}
+		if s.Logging.Debug != nil {
+			cfg.Debug = *s.Logging.Debug
+		}
+	}
+	if s.Transport != nil {
+		if s.Transport.Type != nil {
+			cfg.Transport = *s.Transport.Type
+		}
+		if s.Transport.Listen != nil {
+			cfg.ListenAddr = *s.Transport.Listen
+		}
+		if s.Transport.TokenFile != nil {
+			cfg.TokenFile = *s.Transport.TokenFile
+		}
+		if tls := s.Transport.TLS; tls != nil {
+			if tls.Cert != nil {
+				cfg.TLSCert = *tls.Cert
+			}
+			if tls.Key != nil {
+				cfg.TLSKey = *tls.Key
+			}
+			if tls.ClientCA != nil {
+				cfg.TLSClientCA = *tls.ClientCA
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateConfig checks a resolved configuration for invalid values
+func validateConfig(cfg Config) []string {
+	var problems []string
+
+	if cfg.CacheTTL < 0 {
+		problems = append(problems, fmt.Sprintf("cache TTL must not be negative (got %s)", cfg.CacheTTL))
+	}
+	if cfg.CacheStaleTTL < 0 {
+		problems = append(problems, fmt.Sprintf("cacheStaleTTL must not be negative (got %s)", cfg.CacheStaleTTL))
+	}
+	for prefix, d := range cfg.CacheTTLs {
+		if !slices.ContainsFunc(omnifocus.CacheKeyPrefixes, func(p string) bool { return strings.HasPrefix(prefix, p) }) {
+			problems = append(problems, fmt.Sprintf("unknown cache key prefix %q in cacheTTLs (must start with one of %s)", prefix, strings.Join(omnifocus.CacheKeyPrefixes, ", ")))
+		} else if d < 0 {
+			problems = append(problems, fmt.Sprintf("cache TTL for %s must not be negative (got %s)", prefix, d))
+		}
+	}
+	if cfg.CacheMaxEntries < 0 || cfg.CacheMaxBytes < 0 {
+		problems = append(problems, "cacheLimits must not be negative")
+	}
+	if cfg.MirrorRefreshInterval <= 0 {
+		problems = append(problems, fmt.Sprintf("mirror.refreshInterval must be positive (got %s)", cfg.MirrorRefreshInterval))
+	}
+	if cfg.FullSyncInterval < 0 {
+		problems = append(problems, fmt.Sprintf("incrementalSync.fullSyncInterval must not be negative (got %s)", cfg.FullSyncInterval))
+	}
+	if cfg.ChangeDetectionInterval < 0 {
+		problems = append(problems, fmt.Sprintf("changeDetection.interval must not be negative (got %s)", cfg.ChangeDetectionInterval))
+	}
+	if cfg.Timeout < 0 {
+		problems = append(problems, fmt.Sprintf("timeout must not be negative (got %s)", cfg.Timeout))
+	}
+	for op, d := range cfg.OperationTimeouts {
+		if !slices.Contains(omnifocus.Operations, op) {
+			problems = append(problems, fmt.Sprintf("unknown operation %q in operationTimeouts (valid: %s)", op, strings.Join(omnifocus.Operations, ", ")))
+		} else if d < 0 {
+			problems = append(problems, fmt.Sprintf("timeout for %s must not be negative (got %s)", op, d))
+		}
+	}
+	if cfg.Retry.MaxAttempts < 1 {
+		problems = append(problems, fmt.Sprintf("retry.maxAttempts must be at least 1 (got %d)", cfg.Retry.MaxAttempts))
+	}
+	if cfg.Retry.InitialBackoff < 0 || cfg.Retry.MaxBackoff < 0 {
+		problems = append(problems, "retry backoff durations must not be negative")
+	}
+	if cfg.Retry.Jitter < 0 || cfg.Retry.Jitter > 1 {
+		problems = append(problems, fmt.Sprintf("retry.jitter must be between 0 and 1 (got %g)", cfg.Retry.Jitter))
+	}
+	if cfg.MaxConcurrentReads < 1 {
+		problems = append(problems, fmt.Sprintf("concurrency.reads must be at least 1 (got %d)", cfg.MaxConcurrentReads))
+	}
+	if cfg.MaxConcurrentWrites < 1 {
+		problems = append(problems, fmt.Sprintf("concurrency.writes must be at least 1 (got %d)", cfg.MaxConcurrentWrites))
+	}
+	if !slices.Contains(transports, cfg.Transport) {
+		problems = append(problems, fmt.Sprintf("unknown transport %q (valid: %s)", cfg.Transport, strings.Join(transports, ", ")))
+	} else if cfg.Transport != transportStdio {
+		if _, _, err := net.SplitHostPort(cfg.ListenAddr); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid listen address %q: %v", cfg.ListenAddr, err))
+		}
+		// Anything that can reach the port could otherwise drive OmniFocus
+		if cfg.TokenFile == "" && cfg.TLSClientCA == "" {
+			problems = append(problems, fmt.Sprintf("the %s transport requires transport.tokenFile or transport.tls.clientCA", cfg.Transport))
+		}
+		if cfg.TokenFile != "" {
+			if _, err := loadTokens(cfg.TokenFile); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+			problems = append(problems, "transport.tls.cert and transport.tls.key must be set together")
+		}
+		if cfg.TLSClientCA != "" && cfg.TLSCert == "" {
+			problems = append(problems, "transport.tls.clientCA requires transport.tls.cert and transport.tls.key")
+		}
+	}
+	if cfg.TimeZone != "" {
+		if _, err := time.LoadLocation(cfg.TimeZone); err != nil {
+			problems = append(problems, fmt.Sprintf("unknown time zone %q", cfg.TimeZone))
+		}
+	}
+	if cfg.ScriptsPath != "" {
+		if info, err := os.Stat(cfg.ScriptsPath); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("scripts directory %q does not exist", cfg.ScriptsPath))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resolveFor parses args into a fresh flag set and resolves the config with
+// the given environment. XDG_CONFIG_HOME defaults to an empty temp dir so the
+// developer's own config file is never read.
+func resolveFor(t *testing.T, args []string, env map[string]string) (Config, error) {
+	t.Helper()
+	if _, ok := env["XDG_CONFIG_HOME"]; !ok {
+		env["XDG_CONFIG_HOME"] = t.TempDir()
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := registerConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	return resolveConfig(fs, flags, func(k string) string { return env[k] })
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestResolveConfig_Defaults(t *testing.T) {
+	cfg, err := resolveFor(t, nil, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CacheTTL != 30*time.Second || cfg.ReadOnly || !cfg.Scope.IsEmpty() {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestResolveConfig_DefaultPathIsRead(t *testing.T) {
+	xdg := t.TempDir()
+	os.MkdirAll(filepath.Join(xdg, "mcp-omnifocus"), 0o755)
+	os.WriteFile(filepath.Join(xdg, "mcp-omnifocus", "config.yaml"), []byte("readOnly: true\n"), 0o644)
+
+	cfg, err := resolveFor(t, nil, map[string]string{"XDG_CONFIG_HOME": xdg})
+	if err != nil || !cfg.ReadOnly {
+		t.Fatalf("err=%v cfg=%+v", err, cfg)
+	}
+}
+
+func TestResolveConfig_Precedence(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+cacheTTL: 10s
+timeZone: Europe/Dublin
+timeout: 5s
+`)
+
+	// File only
+	cfg, err := resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CacheTTL != 10*time.Second || cfg.TimeZone != "Europe/Dublin" || cfg.Timeout != 5*time.Second {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+
+	// Env beats file
+	env := map[string]string{"MCP_OMNIFOCUS_CACHE_TTL": "20s", "MCP_OMNIFOCUS_TIMEZONE": "UTC"}
+	cfg, _ = resolveFor(t, []string{"-config", path}, env)
+	if cfg.CacheTTL != 20*time.Second || cfg.TimeZone != "UTC" {
+		t.Errorf("env did not override file: %+v", cfg)
+	}
+
+	// Flag beats env
+	cfg, _ = resolveFor(t, []string{"-config", path, "-cache-ttl", "40s"}, map[string]string{"MCP_OMNIFOCUS_CACHE_TTL": "20s"})
+	if cfg.CacheTTL != 40*time.Second {
+		t.Errorf("flag did not override env: %s", cfg.CacheTTL)
+	}
+}
+
+func TestResolveConfig_CacheTTLInSeconds(t *testing.T) {
+	// Bare numbers of seconds, as the TTL was first configured, still work
+	cfg, err := resolveFor(t, []string{"-cache-ttl", "60"}, map[string]string{})
+	if err != nil || cfg.CacheTTL != time.Minute {
+		t.Errorf("flag: expected 1m, got %s, %v", cfg.CacheTTL, err)
+	}
+	cfg, err = resolveFor(t, nil, map[string]string{"MCP_OMNIFOCUS_CACHE_TTL": "90"})
+	if err != nil || cfg.CacheTTL != 90*time.Second {
+		t.Errorf("env: expected 1m30s, got %s, %v", cfg.CacheTTL, err)
+	}
+	path := writeConfig(t, "config.yaml", "cacheTTL: 120\n")
+	cfg, err = resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil || cfg.CacheTTL != 2*time.Minute {
+		t.Errorf("file: expected 2m, got %s, %v", cfg.CacheTTL, err)
+	}
+}
+
+func TestResolveConfig_Profiles(t *testing.T) {
+	path := writeConfig(t, "config.json", `{
+  "cacheTTL": "1m",
+  "profiles": {
+    "work": {
+      "readOnly": true,
+      "scope": {"denyFolders": ["Personal"]},
+      "logging": {"debug": true}
+    }
+  }
+}`)
+
+	cfg, err := resolveFor(t, []string{"-config", path, "-profile", "work"}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.ReadOnly || !cfg.Debug || cfg.CacheTTL != time.Minute {
+		t.Errorf("profile not applied over top level: %+v", cfg)
+	}
+	if len(cfg.Scope.DenyFolders) != 1 || cfg.Scope.DenyFolders[0] != "Personal" {
+		t.Errorf("profile scope not applied: %+v", cfg.Scope)
+	}
+
+	// Scope flags override the profile's lists
+	cfg, _ = resolveFor(t, []string{"-config", path, "-profile", "work", "-deny-folders", "Home, Family"}, map[string]string{})
+	if len(cfg.Scope.DenyFolders) != 2 || cfg.Scope.DenyFolders[1] != "Family" {
+		t.Errorf("scope flag did not override profile: %+v", cfg.Scope)
+	}
+}
+
+func TestResolveConfig_ReportsAllProblems(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+cacheTTL: soon
+timeZone: Mars/Olympus
+`)
+
+	_, err := resolveFor(t, []string{"-config", path, "-profile", "missing", "-timeout", "-1s"}, map[string]string{
+		"MCP_OMNIFOCUS_READ_ONLY": "maybe",
+		"MCP_OMNIFOCUS_CACHE_TTL": "a minute",
+	})
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	want := []string{"cacheTTL", "Mars/Olympus", `profile "missing"`, "MCP_OMNIFOCUS_READ_ONLY", "MCP_OMNIFOCUS_CACHE_TTL", "timeout must not be negative"}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected problem mentioning %q in:\n%v", w, err)
+		}
+	}
+}
+
+func TestResolveConfig_UnknownKeyAndMissingFile(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "cacheTTl: 10s\n")
+	if _, err := resolveFor(t, []string{"-config", path}, map[string]string{}); err == nil {
+		t.Error("expected error for misspelled key")
+	}
+
+	missing := filepath.Join(t.TempDir(), "nope.yaml")
+	if _, err := resolveFor(t, []string{"-config", missing}, map[string]string{}); err == nil {
+		t.Error("expected error for explicit missing config file")
+	}
+}
+
+func TestResolveConfig_Concurrency(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+concurrency:
+  reads: 4
+`)
+	cfg, err := resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaxConcurrentReads != 4 || cfg.MaxConcurrentWrites != 1 {
+		t.Errorf("unexpected concurrency reads=%d writes=%d", cfg.MaxConcurrentReads, cfg.MaxConcurrentWrites)
+	}
+
+	path = writeConfig(t, "config.yaml", `
+concurrency:
+  writes: 0
+`)
+	if _, err := resolveFor(t, []string{"-config", path}, map[string]string{}); err == nil || !strings.Contains(err.Error(), "concurrency.writes") {
+		t.Errorf("expected concurrency.writes problem, got %v", err)
+	}
+}
+
+func TestResolveConfig_DiskCache(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+diskCache:
+  enabled: true
+  dir: /tmp/of-cache
+`)
+	cfg, err := resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.DiskCache || cfg.DiskCacheDir != "/tmp/of-cache" {
+		t.Errorf("disk cache settings not applied: %+v", cfg)
+	}
+}
+
+func TestResolveConfig_CacheTTLsAndLimits(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+cacheTTLs:
+  "tags:": 10m
+cacheLimits:
+  maxEntries: 50
+  maxBytes: 1048576
+`)
+	cfg, err := resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CacheTTLs["tags:"] != 10*time.Minute || cfg.CacheMaxEntries != 50 || cfg.CacheMaxBytes != 1048576 {
+		t.Errorf("cache settings not applied: %+v", cfg)
+	}
+
+	path = writeConfig(t, "config.yaml", `
+cacheTTLs:
+  "folders:": 1m
+`)
+	if _, err := resolveFor(t, []string{"-config", path}, map[string]string{}); err == nil || !strings.Contains(err.Error(), `"folders:"`) {
+		t.Errorf("expected unknown prefix problem, got %v", err)
+	}
+}
+
+func TestResolveConfig_ChangeDetection(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+changeDetection:
+  interval: 15s
+`)
+	cfg, err := resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil || cfg.ChangeDetectionInterval != 15*time.Second {
+		t.Fatalf("err=%v interval=%s", err, cfg.ChangeDetectionInterval)
+	}
+}
+
+func TestResolveConfig_IncrementalSync(t *testing.T) {
+	cfg, err := resolveFor(t, nil, map[string]string{})
+	if err != nil || cfg.IncrementalSync || cfg.FullSyncInterval != 10*time.Minute {
+		t.Fatalf("unexpected defaults: err=%v enabled=%v interval=%s", err, cfg.IncrementalSync, cfg.FullSyncInterval)
+	}
+
+	path := writeConfig(t, "config.yaml", `
+incrementalSync:
+  enabled: true
+  fullSyncInterval: 1h
+`)
+	cfg, err = resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil || !cfg.IncrementalSync || cfg.FullSyncInterval != time.Hour {
+		t.Fatalf("err=%v enabled=%v interval=%s", err, cfg.IncrementalSync, cfg.FullSyncInterval)
+	}
+
+	path = writeConfig(t, "bad.yaml", `
+incrementalSync:
+  fullSyncInterval: -1m
+`)
+	if _, err := resolveFor(t, []string{"-config", path}, map[string]string{}); err == nil {
+		t.Error("expected a negative full sync interval to be rejected")
+	}
+}
+
+func TestResolveConfig_Mirror(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+mirror:
+  enabled: true
+  path: /tmp/omnifocus.db
+  refreshInterval: 1m
+`)
+	cfg, err := resolveFor(t, []string{"-config", path}, map[string]string{})
+	if err != nil || !cfg.Mirror || cfg.MirrorPath != "/tmp/omnifocus.db" || cfg.MirrorRefreshInterval != time.Minute {
+		t.Fatalf("err=%v cfg=%+v", err, cfg)
+	}
+
+	path = writeConfig(t, "bad.yaml", `
+mirror:
+  refreshInterval: 0s
+`)
+	if _, err := resolveFor(t, []string{"-config", path}, map[string]string{}); err == nil {
+		t.Error("expected a zero refresh interval to be rejected")
+	}
+}
+
+func TestResolveConfig_Transport(t *testing.T) {
+	cfg, err := resolveFor(t, nil, map[string]string{})
+	if err != nil || cfg.Transport != "stdio" || cfg.ListenAddr != "127.0.0.1:8080" {
+		t.Fatalf("err=%v cfg=%+v", err, cfg)
+	}
+
+	path := writeConfig(t, "config.yaml", `
+transport:
+  type: sse
+  listen: 127.0.0.1:9000
+`)
+	cfg, err = resolveFor(t, []string{"-config", path, "-token-file", writeTokenFile(t, testTokens)}, map[string]string{})
+	if err != nil || cfg.Transport != "sse" || cfg.ListenAddr != "127.0.0.1:9000" {
+		t.Fatalf("err=%v cfg=%+v", err, cfg)
+	}
+
+	// Flags override the environment, which overrides the file
+	env := map[string]string{
+		"MCP_OMNIFOCUS_TRANSPORT":  "http",
+		"MCP_OMNIFOCUS_LISTEN":     ":9001",
+		"MCP_OMNIFOCUS_TOKEN_FILE": writeTokenFile(t, testTokens),
+	}
+	cfg, err = resolveFor(t, []string{"-config", path, "-listen", ":9002"}, env)
+	if err != nil || cfg.Transport != "http" || cfg.ListenAddr != ":9002" {
+		t.Fatalf("err=%v cfg=%+v", err, cfg)
+	}
+
+	if _, err := resolveFor(t, []string{"-transport", "websocket"}, map[string]string{}); err == nil {
+		t.Error("expected an unknown transport to be rejected")
+	}
+	if _, err := resolveFor(t, []string{"-transport", "http", "-listen", "8080"}, map[string]string{}); err == nil {
+		t.Error("expected a listen address without a port to be rejected")
+	}
+}
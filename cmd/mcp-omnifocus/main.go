@@ -1,12 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -21,54 +22,154 @@ const (
 )
 
 func main() {
-	// Define command line flags
-	scriptsPath := flag.String("scripts", "", "Path to the JXA scripts directory (if not specified, auto-detection is used)")
-	cacheTTL := flag.Int("cache-ttl", 30, "Cache TTL in seconds (0 to disable caching)")
+	flags := registerConfigFlags(flag.CommandLine)
 	flag.Parse()
 
-	// Check for environment variable override
-	cacheTTLSeconds := *cacheTTL
-	if envTTL := os.Getenv("MCP_OMNIFOCUS_CACHE_TTL"); envTTL != "" {
-		if ttl, err := strconv.Atoi(envTTL); err == nil {
-			cacheTTLSeconds = ttl
-		}
+	cfg, err := resolveConfig(flag.CommandLine, flags, os.Getenv)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Create OmniFocus client with caching
-	ttlDuration := time.Duration(cacheTTLSeconds) * time.Second
+	if cfg.LogFile != "" {
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatalf("Failed to open log file: %v", err)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+	}
+	if cfg.Debug {
+		// Script path detection reads the debug switch from the environment
+		os.Setenv("MCP_OMNIFOCUS_DEBUG", "1")
+	}
 
 	// Create a temporary client to get the scripts directory
-	tempClient := omnifocus.NewClient()
-	scriptsDir := *scriptsPath
+	scriptsDir := cfg.ScriptsPath
 	if scriptsDir == "" {
-		scriptsDir = tempClient.GetScriptsDir()
+		scriptsDir = omnifocus.NewClient().GetScriptsDir()
 	}
 
-	ofClient := omnifocus.NewClientWithCache(scriptsDir, ttlDuration)
+	// Create OmniFocus client with caching
+	ofClient := omnifocus.NewClientWithCache(scriptsDir, cfg.CacheTTL)
+	ofClient.SetStaleTTL(cfg.CacheStaleTTL)
+	ofClient.SetCacheLimits(cfg.CacheMaxEntries, cfg.CacheMaxBytes)
+	for prefix, ttl := range cfg.CacheTTLs {
+		// Prefixes were checked when the config was resolved
+		ofClient.SetCacheTTL(prefix, ttl)
+	}
+	ofClient.SetTimeZone(cfg.TimeZone)
+	ofClient.SetTimeout(cfg.Timeout)
+	for op, timeout := range cfg.OperationTimeouts {
+		ofClient.SetOperationTimeout(op, timeout)
+	}
+	ofClient.SetRetryPolicy(cfg.Retry)
+	ofClient.SetConcurrency(cfg.MaxConcurrentReads, cfg.MaxConcurrentWrites)
+	if cfg.IncrementalSync {
+		ofClient.SetIncrementalSync(cfg.FullSyncInterval)
+		log.Printf("Incremental task sync enabled (full read every %s)", cfg.FullSyncInterval)
+	}
 
 	// Log cache configuration
-	if cacheTTLSeconds > 0 {
-		log.Printf("Cache enabled with TTL: %d seconds", cacheTTLSeconds)
+	if cfg.CacheTTL > 0 {
+		log.Printf("Cache enabled with TTL: %s (stale for up to %s while refreshing)", cfg.CacheTTL, cfg.CacheStaleTTL)
+		if cfg.DiskCache {
+			enableDiskCache(ofClient, cfg.DiskCacheDir)
+		}
+		if cfg.ChangeDetectionInterval > 0 {
+			ofClient.StartChangeDetection(cfg.ChangeDetectionInterval)
+			log.Printf("Change detection enabled every %s", cfg.ChangeDetectionInterval)
+		}
 	} else {
 		log.Printf("Cache disabled")
 	}
 
-	// Create MCP server
+	// Restrict the assistant to the configured folders, projects and tags
+	var client omnifocus.OmniFocusClient = ofClient
+	var scoped *omnifocus.ScopedClient
+	if !cfg.Scope.IsEmpty() {
+		scoped = omnifocus.NewScopedClient(client, cfg.Scope)
+		client = scoped
+		log.Printf("Scope restrictions enabled")
+	}
+	if cfg.ReadOnly {
+		client = omnifocus.NewReadOnlyClient(client)
+		log.Printf("Read-only mode enabled")
+	}
+	if cfg.Transport != transportStdio {
+		// Network callers are limited to their token's scope
+		client = authorizedClient{client}
+	}
+
+	// Create MCP server. Resources support subscriptions, which are told
+	// about writes and detected changes, and tool calls report their
+	// progress to clients that ask for it.
+	subs := newSubscriptions()
 	s := server.NewMCPServer(
 		serverName,
 		serverVersion,
+		server.WithResourceCapabilities(true, true),
+		server.WithHooks(subs.hooks()),
+		server.WithToolHandlerMiddleware(progressMiddleware),
 	)
+	subs.attach(s)
+	if scoped != nil {
+		subs.restrict(scoped)
+	}
+	ofClient.OnChange(subs.publish)
 
 	// Register tools
-	registerTools(s, ofClient)
+	registerTools(s, client)
+	registerCacheTools(s, ofClient)
+	registerResources(s, client)
+	registerPrompts(s, client, location(cfg.TimeZone))
+	if cfg.Mirror {
+		// The mirror reads through client so it honours the scope
+		mirror, err := omnifocus.NewMirror(client, cfg.MirrorPath)
+		if err != nil {
+			log.Fatalf("Failed to create mirror: %v", err)
+		}
+		defer mirror.Close()
+		ofClient.OnChange(func(omnifocus.Change) { mirror.MarkDirty() })
+		stop := mirror.Start(cfg.MirrorRefreshInterval)
+		defer stop()
+		registerQueryTools(s, mirror)
+		log.Printf("SQLite mirror enabled (refreshed every %s)", cfg.MirrorRefreshInterval)
+	}
 
-	// Start server with stdio transport
-	if err := server.ServeStdio(s); err != nil {
+	if err := serve(s, cfg); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
+// location returns the named time zone, or the local one if name is empty.
+// Names were checked when the config was resolved.
+func location(name string) *time.Location {
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc
+	}
+	return time.Local
+}
+
+// enableDiskCache persists the client's cache in dir, or the user cache
+// directory if dir is empty. Failures are logged and leave the memory cache
+// in place.
+func enableDiskCache(client *omnifocus.Client, dir string) {
+	if dir == "" {
+		var err error
+		if dir, err = omnifocus.DefaultDiskCacheDir(); err != nil {
+			log.Printf("Disk cache disabled: %v", err)
+			return
+		}
+	}
+	if err := client.SetDiskCache(dir); err != nil {
+		log.Printf("Disk cache disabled: %v", err)
+		return
+	}
+	log.Printf("Disk cache enabled in %s", dir)
+}
+
 // splitTags splits a comma-separated tag string, trimming surrounding spaces.
+// It is also used for the comma-separated scope flags.
 func splitTags(tagsStr string) []string {
 	if tagsStr == "" {
 		return nil
@@ -83,40 +184,71 @@ func splitTags(tagsStr string) []string {
 	return tags
 }
 
+// errorHints suggests a fix for error codes the user can act on
+var errorHints = map[omnifocus.ErrorCode]string{
+	omnifocus.CodePermissionDenied: "Allow automation of OmniFocus in System Settings > Privacy & Security > Automation.",
+	omnifocus.CodeAppNotRunning:    "Open OmniFocus and try again.",
+	omnifocus.CodeTimeout:          "Check whether OmniFocus is showing a dialog or is busy syncing, then try again.",
+}
+
+// toolError converts a client error into a tool error result. The error code
+// is included in the message and in the result's _meta.errorCode so clients
+// can tell failure classes apart.
+func toolError(action string, err error) *mcp.CallToolResult {
+	code := omnifocus.CodeOf(err)
+
+	msg := fmt.Sprintf("Failed to %s: %v", action, err)
+	var timeoutErr *omnifocus.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		msg = fmt.Sprintf("Failed to %s: OmniFocus did not respond within %s.", action, timeoutErr.Timeout)
+	}
+	if hint, ok := errorHints[code]; ok {
+		msg += " " + hint
+	}
+
+	result := mcp.NewToolResultError(fmt.Sprintf("%s (error code: %s)", msg, code))
+	result.Meta = mcp.NewMetaFromMap(map[string]any{"errorCode": string(code)})
+	return result
+}
+
 func registerTools(s *server.MCPServer, client omnifocus.OmniFocusClient) {
 	// List Projects Tool
-	listProjectsTool := mcp.NewTool("list_projects",
+	listProjectsTool := newTool("list_projects",
 		mcp.WithDescription("List all projects in OmniFocus"),
+		mcp.WithOutputSchema[projectList](),
 		mcp.WithString("filter",
 			mcp.Description("Optional filter for project status (active, on-hold, completed, dropped)"),
 		),
 	)
-	s.AddTool(listProjectsTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleListProjects(client, args)
+	s.AddTool(listProjectsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleListProjects(ctx, client, request.GetArguments())
 	})
 
 	// List Tasks Tool
-	listTasksTool := mcp.NewTool("list_tasks",
+	listTasksTool := newTool("list_tasks",
 		mcp.WithDescription("List tasks in OmniFocus, optionally filtered by project"),
+		mcp.WithOutputSchema[taskList](),
 		mcp.WithString("project_id",
 			mcp.Description("Optional project ID to filter tasks"),
 		),
 	)
-	s.AddTool(listTasksTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleListTasks(client, args)
+	s.AddTool(listTasksTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleListTasks(ctx, client, request.GetArguments())
 	})
 
 	// List Tags Tool
-	listTagsTool := mcp.NewTool("list_tags",
+	listTagsTool := newTool("list_tags",
 		mcp.WithDescription("List all tags in OmniFocus"),
+		mcp.WithOutputSchema[tagList](),
 	)
-	s.AddTool(listTagsTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleListTags(client, args)
+	s.AddTool(listTagsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleListTags(ctx, client, request.GetArguments())
 	})
 
 	// Create Task Tool
-	createTaskTool := mcp.NewTool("create_task",
+	createTaskTool := newTool("create_task",
 		mcp.WithDescription("Create a new task in OmniFocus"),
+		mcp.WithOutputSchema[omnifocus.OperationResult](),
 		mcp.WithString("name",
 			mcp.Description("Task name (required)"),
 			mcp.Required(),
@@ -140,13 +272,14 @@ func registerTools(s *server.MCPServer, client omnifocus.OmniFocusClient) {
 			mcp.Description("Comma-separated list of tag names"),
 		),
 	)
-	s.AddTool(createTaskTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleCreateTask(client, args)
+	s.AddTool(createTaskTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleCreateTask(ctx, client, request.GetArguments())
 	})
 
 	// Create Project Tool
-	createProjectTool := mcp.NewTool("create_project",
+	createProjectTool := newTool("create_project",
 		mcp.WithDescription("Create a new project in OmniFocus"),
+		mcp.WithOutputSchema[omnifocus.OperationResult](),
 		mcp.WithString("name",
 			mcp.Description("Project name (required)"),
 			mcp.Required(),
@@ -161,13 +294,14 @@ func registerTools(s *server.MCPServer, client omnifocus.OmniFocusClient) {
 			mcp.Description("Comma-separated list of tag names"),
 		),
 	)
-	s.AddTool(createProjectTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleCreateProject(client, args)
+	s.AddTool(createProjectTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleCreateProject(ctx, client, request.GetArguments())
 	})
 
 	// Update Task Tool
-	updateTaskTool := mcp.NewTool("update_task",
+	updateTaskTool := newTool("update_task",
 		mcp.WithDescription("Update an existing task in OmniFocus"),
+		mcp.WithOutputSchema[omnifocus.OperationResult](),
 		mcp.WithString("id",
 			mcp.Description("Task ID (required)"),
 			mcp.Required(),
@@ -185,173 +319,267 @@ func registerTools(s *server.MCPServer, client omnifocus.OmniFocusClient) {
 			mcp.Description("Flag or unflag the task"),
 		),
 		mcp.WithString("due_date",
-			mcp.Description("New due date in ISO 8601 format (or null to remove)"),
+			mcp.Description("New due date in ISO 8601 format, or null to remove the due date"),
 		),
 		mcp.WithNumber("estimated_minutes",
 			mcp.Description("New estimated time in minutes"),
 		),
 	)
-	s.AddTool(updateTaskTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleUpdateTask(client, args)
+	s.AddTool(updateTaskTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleUpdateTask(ctx, client, request.GetArguments())
 	})
 
 	// Complete Task Tool
-	completeTaskTool := mcp.NewTool("complete_task",
+	completeTaskTool := newTool("complete_task",
 		mcp.WithDescription("Mark a task as complete in OmniFocus"),
+		mcp.WithOutputSchema[omnifocus.OperationResult](),
 		mcp.WithString("id",
 			mcp.Description("Task ID (required)"),
 			mcp.Required(),
 		),
 	)
-	s.AddTool(completeTaskTool, func(args map[string]interface{}) (*mcp.CallToolResult, error) {
-		return handleCompleteTask(client, args)
+	s.AddTool(completeTaskTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleCompleteTask(ctx, client, request.GetArguments())
+	})
+}
+
+// The list tools' structured results. An output schema must describe an
+// object, so each list is wrapped in one.
+type projectList struct {
+	Projects []omnifocus.Project `json:"projects"`
+}
+
+type taskList struct {
+	Tasks []omnifocus.Task `json:"tasks"`
+}
+
+type tagList struct {
+	Tags []omnifocus.Tag `json:"tags"`
+}
+
+// listResult returns structured as the structured content, with items as
+// JSON text for clients that don't read it, and reports in _meta whether the
+// list was served stale from the cache and how old it is
+func listResult(items, structured any, freshness *omnifocus.Freshness) *mcp.CallToolResult {
+	data, _ := json.MarshalIndent(items, "", "  ")
+	result := mcp.NewToolResultStructured(structured, string(data))
+	result.Meta = mcp.NewMetaFromMap(map[string]any{
+		"stale":     freshness.Stale(),
+		"dataAgeMs": freshness.Age().Milliseconds(),
 	})
+	return result
 }
 
-func handleListProjects(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	projects, err := client.ListProjects()
+func handleListProjects(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	a := newToolArgs(args)
+	filter, _ := a.string("filter")
+	if err := a.err(); err != nil {
+		return toolError("list projects", err), nil
+	}
+
+	ctx, freshness := omnifocus.WithFreshness(ctx)
+	projects, err := client.ListProjects(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to list projects: %v", err)), nil
+		return toolError("list projects", err), nil
 	}
 
-	if filterVal, ok := args["filter"].(string); ok && filterVal != "" {
+	if filter != "" {
 		filtered := []omnifocus.Project{}
 		for _, p := range projects {
-			if p.Status == filterVal {
+			if p.Status == filter {
 				filtered = append(filtered, p)
 			}
 		}
 		projects = filtered
 	}
 
-	result, _ := json.MarshalIndent(projects, "", "  ")
-	return mcp.NewToolResultText(string(result)), nil
+	return listResult(projects, projectList{projects}, freshness), nil
 }
 
-func handleListTasks(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	projectID := ""
-	if pid, ok := args["project_id"].(string); ok {
-		projectID = pid
+func handleListTasks(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	a := newToolArgs(args)
+	projectID, _ := a.string("project_id")
+	if err := a.err(); err != nil {
+		return toolError("list tasks", err), nil
 	}
 
-	tasks, err := client.ListTasks(projectID)
+	ctx, freshness := omnifocus.WithFreshness(ctx)
+	tasks, err := client.ListTasks(ctx, projectID)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to list tasks: %v", err)), nil
+		return toolError("list tasks", err), nil
 	}
 
-	result, _ := json.MarshalIndent(tasks, "", "  ")
-	return mcp.NewToolResultText(string(result)), nil
+	return listResult(tasks, taskList{tasks}, freshness), nil
 }
 
-func handleListTags(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	tags, err := client.ListTags()
+func handleListTags(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	ctx, freshness := omnifocus.WithFreshness(ctx)
+	tags, err := client.ListTags(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to list tags: %v", err)), nil
+		return toolError("list tags", err), nil
 	}
 
-	result, _ := json.MarshalIndent(tags, "", "  ")
-	return mcp.NewToolResultText(string(result)), nil
+	return listResult(tags, tagList{tags}, freshness), nil
 }
 
-func handleCreateTask(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	req := omnifocus.CreateTaskRequest{
-		Name: args["name"].(string),
+// operationResult returns the result of a write as structured content and
+// as JSON text
+func operationResult(result *omnifocus.OperationResult) *mcp.CallToolResult {
+	data, _ := json.MarshalIndent(result, "", "  ")
+	if result == nil {
+		return mcp.NewToolResultText(string(data))
 	}
+	return mcp.NewToolResultStructured(result, string(data))
+}
 
-	if note, ok := args["note"].(string); ok {
-		req.Note = note
-	}
-	if projectID, ok := args["project_id"].(string); ok {
-		req.ProjectID = projectID
-	}
-	if dueDate, ok := args["due_date"].(string); ok {
-		req.DueDate = dueDate
-	}
-	if flagged, ok := args["flagged"].(bool); ok {
-		req.Flagged = flagged
-	}
-	if estimatedMinutes, ok := args["estimated_minutes"].(float64); ok {
-		req.EstimatedMinutes = int(estimatedMinutes)
-	}
-	if tagsStr, ok := args["tags"].(string); ok {
-		req.Tags = splitTags(tagsStr)
+func handleCreateTask(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	req, err := decodeCreateTask(args)
+	if err != nil {
+		return toolError("create task", err), nil
 	}
 
-	result, err := client.CreateTask(req)
+	result, err := client.CreateTask(ctx, req)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to create task: %v", err)), nil
+		return toolError("create task", err), nil
 	}
 
-	resultJSON, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(resultJSON)), nil
+	return operationResult(result), nil
 }
 
-func handleCreateProject(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	req := omnifocus.CreateProjectRequest{
-		Name: args["name"].(string),
+func handleCreateProject(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	req, err := decodeCreateProject(args)
+	if err != nil {
+		return toolError("create project", err), nil
 	}
 
-	if note, ok := args["note"].(string); ok {
-		req.Note = note
-	}
-	if status, ok := args["status"].(string); ok {
-		req.Status = status
+	result, err := client.CreateProject(ctx, req)
+	if err != nil {
+		return toolError("create project", err), nil
 	}
-	if tagsStr, ok := args["tags"].(string); ok {
-		req.Tags = splitTags(tagsStr)
+
+	return operationResult(result), nil
+}
+
+func handleUpdateTask(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	req, err := decodeUpdateTask(args)
+	if err != nil {
+		return toolError("update task", err), nil
 	}
 
-	result, err := client.CreateProject(req)
+	result, err := client.UpdateTask(ctx, req)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to create project: %v", err)), nil
+		return toolError("update task", err), nil
 	}
 
-	resultJSON, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(resultJSON)), nil
+	return operationResult(result), nil
 }
 
-func handleUpdateTask(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	req := omnifocus.UpdateTaskRequest{
-		ID: args["id"].(string),
+func handleCompleteTask(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	a := newToolArgs(args)
+	taskID := a.requiredString("id")
+	if err := a.err(); err != nil {
+		return toolError("complete task", err), nil
 	}
 
-	if name, ok := args["name"].(string); ok {
-		req.Name = &name
-	}
-	if note, ok := args["note"].(string); ok {
-		req.Note = &note
+	result, err := client.CompleteTask(ctx, taskID)
+	if err != nil {
+		return toolError("complete task", err), nil
 	}
-	if completed, ok := args["completed"].(bool); ok {
-		req.Completed = &completed
+
+	return operationResult(result), nil
+}
+
+// cacheAdmin is implemented by clients whose cache can be inspected and
+// cleared
+type cacheAdmin interface {
+	CacheStats() map[string]omnifocus.CacheStats
+	ClearCache(prefix string) error
+}
+
+func registerCacheTools(s *server.MCPServer, cache cacheAdmin) {
+	// Cache Stats Tool
+	cacheStatsTool := newTool("cache_stats",
+		mcp.WithDescription("Show hit, miss, eviction and invalidation counts for the project, task and tag caches"),
+	)
+	s.AddTool(cacheStatsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleCacheStats(ctx, cache, request.GetArguments())
+	})
+
+	// Cache Clear Tool
+	cacheClearTool := newTool("cache_clear",
+		mcp.WithDescription("Clear cached lists, e.g. after editing OmniFocus directly"),
+		mcp.WithString("prefix",
+			mcp.Description("Optional cache key prefix to clear (projects:, tasks:, tasks:project:<id>, tags:); clears everything if omitted"),
+		),
+	)
+	s.AddTool(cacheClearTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleCacheClear(ctx, cache, request.GetArguments())
+	})
+}
+
+func handleCacheStats(ctx context.Context, cache cacheAdmin, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	result, _ := json.MarshalIndent(cache.CacheStats(), "", "  ")
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCacheClear(ctx context.Context, cache cacheAdmin, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	a := newToolArgs(args)
+	prefix, _ := a.string("prefix")
+	if err := a.err(); err != nil {
+		return toolError("clear cache", err), nil
 	}
-	if flagged, ok := args["flagged"].(bool); ok {
-		req.Flagged = &flagged
+	// Clearing the cache sends every caller's next read to OmniFocus, so
+	// it needs a token that may write
+	if err := authorize(ctx, "cache_clear"); err != nil {
+		return toolError("clear cache", err), nil
 	}
-	if dueDate, ok := args["due_date"].(string); ok {
-		req.DueDate = &dueDate
+	target := "all"
+	if prefix != "" {
+		target = fmt.Sprintf("%q", prefix)
 	}
-	if estimatedMinutes, ok := args["estimated_minutes"].(float64); ok {
-		minutes := int(estimatedMinutes)
-		req.EstimatedMinutes = &minutes
+	err := cache.ClearCache(prefix)
+	logWrite(ctx, "cache_clear", target, err)
+	if err != nil {
+		return toolError("clear cache", err), nil
 	}
 
-	result, err := client.UpdateTask(req)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to update task: %v", err)), nil
+	if prefix == "" {
+		return mcp.NewToolResultText("Cleared all cached lists"), nil
 	}
+	return mcp.NewToolResultText(fmt.Sprintf("Cleared cached lists matching %s", prefix)), nil
+}
 
-	resultJSON, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(resultJSON)), nil
+// databaseQuerier runs read-only SQL against a mirror of the database
+type databaseQuerier interface {
+	Query(ctx context.Context, query string, args ...any) (*omnifocus.QueryResult, error)
+}
+
+func registerQueryTools(s *server.MCPServer, db databaseQuerier) {
+	// Query Database Tool
+	queryTool := newTool("query_database",
+		mcp.WithDescription(fmt.Sprintf("Run a read-only SQLite SELECT over a local mirror of OmniFocus, e.g. to join tasks, projects, tags and folders. "+
+			"Returns at most %d rows. Schema:\n%s", omnifocus.MaxQueryRows, omnifocus.MirrorSchema)),
+		mcp.WithString("sql",
+			mcp.Description("A single SELECT or WITH statement"),
+			mcp.Required(),
+		),
+	)
+	s.AddTool(queryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleQueryDatabase(ctx, db, request.GetArguments())
+	})
 }
 
-func handleCompleteTask(client omnifocus.OmniFocusClient, args map[string]interface{}) (*mcp.CallToolResult, error) {
-	taskID := args["id"].(string)
+func handleQueryDatabase(ctx context.Context, db databaseQuerier, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	a := newToolArgs(args)
+	query := a.requiredString("sql")
+	if err := a.err(); err != nil {
+		return toolError("query database", err), nil
+	}
 
-	result, err := client.CompleteTask(taskID)
+	result, err := db.Query(ctx, query)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to complete task: %v", err)), nil
+		return toolError("query database", err), nil
 	}
-
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	return mcp.NewToolResultText(string(resultJSON)), nil
 }
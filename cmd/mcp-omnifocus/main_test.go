@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/conall/mcp-omnifocus/internal/omnifocus"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
 // ---------- mockClient ----------
@@ -25,24 +28,24 @@ type mockClient struct {
 	lastCompleteTaskID   string
 }
 
-func (m *mockClient) ListProjects() ([]omnifocus.Project, error) { return m.projects, m.err }
-func (m *mockClient) ListTasks(projectID string) ([]omnifocus.Task, error) {
+func (m *mockClient) ListProjects(ctx context.Context) ([]omnifocus.Project, error) { return m.projects, m.err }
+func (m *mockClient) ListTasks(ctx context.Context, projectID string) ([]omnifocus.Task, error) {
 	return m.tasks, m.err
 }
-func (m *mockClient) ListTags() ([]omnifocus.Tag, error) { return m.tags, m.err }
-func (m *mockClient) CreateTask(req omnifocus.CreateTaskRequest) (*omnifocus.OperationResult, error) {
+func (m *mockClient) ListTags(ctx context.Context) ([]omnifocus.Tag, error) { return m.tags, m.err }
+func (m *mockClient) CreateTask(ctx context.Context, req omnifocus.CreateTaskRequest) (*omnifocus.OperationResult, error) {
 	m.lastCreateTaskReq = req
 	return m.result, m.err
 }
-func (m *mockClient) CreateProject(req omnifocus.CreateProjectRequest) (*omnifocus.OperationResult, error) {
+func (m *mockClient) CreateProject(ctx context.Context, req omnifocus.CreateProjectRequest) (*omnifocus.OperationResult, error) {
 	m.lastCreateProjectReq = req
 	return m.result, m.err
 }
-func (m *mockClient) UpdateTask(req omnifocus.UpdateTaskRequest) (*omnifocus.OperationResult, error) {
+func (m *mockClient) UpdateTask(ctx context.Context, req omnifocus.UpdateTaskRequest) (*omnifocus.OperationResult, error) {
 	m.lastUpdateTaskReq = req
 	return m.result, m.err
 }
-func (m *mockClient) CompleteTask(taskID string) (*omnifocus.OperationResult, error) {
+func (m *mockClient) CompleteTask(ctx context.Context, taskID string) (*omnifocus.OperationResult, error) {
 	m.lastCompleteTaskID = taskID
 	return m.result, m.err
 }
@@ -89,26 +92,28 @@ func TestSplitTags_TrailingComma(t *testing.T) {
 // ---------- handleListProjects ----------
 
 func TestHandleListProjects_ReturnsProjects(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{
 		projects: []omnifocus.Project{
 			{ID: "p1", Name: "Alpha", Status: "active"},
 			{ID: "p2", Name: "Beta", Status: "on-hold"},
 		},
 	}
-	res, err := handleListProjects(m, map[string]interface{}{})
+	res, err := handleListProjects(ctx, m, map[string]interface{}{})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
 }
 
 func TestHandleListProjects_Filter(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{
 		projects: []omnifocus.Project{
 			{ID: "p1", Name: "Active", Status: "active"},
 			{ID: "p2", Name: "OnHold", Status: "on-hold"},
 		},
 	}
-	res, err := handleListProjects(m, map[string]interface{}{"filter": "active"})
+	res, err := handleListProjects(ctx, m, map[string]interface{}{"filter": "active"})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -122,8 +127,9 @@ func TestHandleListProjects_Filter(t *testing.T) {
 }
 
 func TestHandleListProjects_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("OmniFocus unavailable")}
-	res, err := handleListProjects(m, map[string]interface{}{})
+	res, err := handleListProjects(ctx, m, map[string]interface{}{})
 	if err != nil {
 		t.Fatalf("handler should not return Go error: %v", err)
 	}
@@ -135,26 +141,29 @@ func TestHandleListProjects_Error(t *testing.T) {
 // ---------- handleListTasks ----------
 
 func TestHandleListTasks_All(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{
 		tasks: []omnifocus.Task{{ID: "t1", Name: "Task one"}},
 	}
-	res, err := handleListTasks(m, map[string]interface{}{})
+	res, err := handleListTasks(ctx, m, map[string]interface{}{})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
 }
 
 func TestHandleListTasks_WithProjectID(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{tasks: []omnifocus.Task{}}
-	res, err := handleListTasks(m, map[string]interface{}{"project_id": "proj-1"})
+	res, err := handleListTasks(ctx, m, map[string]interface{}{"project_id": "proj-1"})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
 }
 
 func TestHandleListTasks_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("fail")}
-	res, err := handleListTasks(m, map[string]interface{}{})
+	res, err := handleListTasks(ctx, m, map[string]interface{}{})
 	if err != nil || !res.IsError {
 		t.Errorf("expected IsError=true, got err=%v isError=%v", err, res.IsError)
 	}
@@ -163,10 +172,11 @@ func TestHandleListTasks_Error(t *testing.T) {
 // ---------- handleListTags ----------
 
 func TestHandleListTags_ReturnsTags(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{
 		tags: []omnifocus.Tag{{ID: "tag1", Name: "home"}, {ID: "tag2", Name: "work"}},
 	}
-	res, err := handleListTags(m, map[string]interface{}{})
+	res, err := handleListTags(ctx, m, map[string]interface{}{})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -177,8 +187,9 @@ func TestHandleListTags_ReturnsTags(t *testing.T) {
 }
 
 func TestHandleListTags_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("fail")}
-	res, err := handleListTags(m, map[string]interface{}{})
+	res, err := handleListTags(ctx, m, map[string]interface{}{})
 	if err != nil || !res.IsError {
 		t.Errorf("expected IsError=true")
 	}
@@ -187,8 +198,9 @@ func TestHandleListTags_Error(t *testing.T) {
 // ---------- handleCreateTask ----------
 
 func TestHandleCreateTask_BasicInbox(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Name: "Buy milk", Success: true}}
-	res, err := handleCreateTask(m, map[string]interface{}{"name": "Buy milk"})
+	res, err := handleCreateTask(ctx, m, map[string]interface{}{"name": "Buy milk"})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -198,6 +210,7 @@ func TestHandleCreateTask_BasicInbox(t *testing.T) {
 }
 
 func TestHandleCreateTask_AllFields(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Name: "T", Success: true}}
 	args := map[string]interface{}{
 		"name":               "T",
@@ -208,7 +221,7 @@ func TestHandleCreateTask_AllFields(t *testing.T) {
 		"estimated_minutes":  float64(30),
 		"tags":               "home, work",
 	}
-	res, err := handleCreateTask(m, args)
+	res, err := handleCreateTask(ctx, m, args)
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -220,8 +233,9 @@ func TestHandleCreateTask_AllFields(t *testing.T) {
 }
 
 func TestHandleCreateTask_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("create failed")}
-	res, err := handleCreateTask(m, map[string]interface{}{"name": "T"})
+	res, err := handleCreateTask(ctx, m, map[string]interface{}{"name": "T"})
 	if err != nil || !res.IsError {
 		t.Errorf("expected IsError=true")
 	}
@@ -230,8 +244,9 @@ func TestHandleCreateTask_Error(t *testing.T) {
 // ---------- handleCreateProject ----------
 
 func TestHandleCreateProject_Basic(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "p1", Name: "Work", Success: true}}
-	res, err := handleCreateProject(m, map[string]interface{}{"name": "Work"})
+	res, err := handleCreateProject(ctx, m, map[string]interface{}{"name": "Work"})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -241,6 +256,7 @@ func TestHandleCreateProject_Basic(t *testing.T) {
 }
 
 func TestHandleCreateProject_WithStatusAndTags(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "p1", Name: "P", Success: true}}
 	args := map[string]interface{}{
 		"name":   "P",
@@ -248,7 +264,7 @@ func TestHandleCreateProject_WithStatusAndTags(t *testing.T) {
 		"status": "active",
 		"tags":   "work,home",
 	}
-	res, err := handleCreateProject(m, args)
+	res, err := handleCreateProject(ctx, m, args)
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -259,8 +275,9 @@ func TestHandleCreateProject_WithStatusAndTags(t *testing.T) {
 }
 
 func TestHandleCreateProject_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("fail")}
-	res, err := handleCreateProject(m, map[string]interface{}{"name": "P"})
+	res, err := handleCreateProject(ctx, m, map[string]interface{}{"name": "P"})
 	if err != nil || !res.IsError {
 		t.Errorf("expected IsError=true")
 	}
@@ -269,13 +286,14 @@ func TestHandleCreateProject_Error(t *testing.T) {
 // ---------- handleUpdateTask ----------
 
 func TestHandleUpdateTask_NameAndFlag(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Name: "New", Success: true}}
 	args := map[string]interface{}{
 		"id":      "t1",
 		"name":    "New",
 		"flagged": true,
 	}
-	res, err := handleUpdateTask(m, args)
+	res, err := handleUpdateTask(ctx, m, args)
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -287,6 +305,7 @@ func TestHandleUpdateTask_NameAndFlag(t *testing.T) {
 }
 
 func TestHandleUpdateTask_AllOptionals(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Name: "T", Success: true}}
 	args := map[string]interface{}{
 		"id":                "t1",
@@ -295,7 +314,7 @@ func TestHandleUpdateTask_AllOptionals(t *testing.T) {
 		"due_date":          "2025-01-01T00:00:00Z",
 		"estimated_minutes": float64(60),
 	}
-	res, err := handleUpdateTask(m, args)
+	res, err := handleUpdateTask(ctx, m, args)
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -307,8 +326,9 @@ func TestHandleUpdateTask_AllOptionals(t *testing.T) {
 }
 
 func TestHandleUpdateTask_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("fail")}
-	res, err := handleUpdateTask(m, map[string]interface{}{"id": "t1"})
+	res, err := handleUpdateTask(ctx, m, map[string]interface{}{"id": "t1"})
 	if err != nil || !res.IsError {
 		t.Errorf("expected IsError=true")
 	}
@@ -317,8 +337,9 @@ func TestHandleUpdateTask_Error(t *testing.T) {
 // ---------- handleCompleteTask ----------
 
 func TestHandleCompleteTask_Success(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{result: &omnifocus.OperationResult{ID: "t1", Name: "Done", Success: true}}
-	res, err := handleCompleteTask(m, map[string]interface{}{"id": "t1"})
+	res, err := handleCompleteTask(ctx, m, map[string]interface{}{"id": "t1"})
 	if err != nil || res.IsError {
 		t.Fatalf("err=%v isError=%v", err, res.IsError)
 	}
@@ -328,8 +349,9 @@ func TestHandleCompleteTask_Success(t *testing.T) {
 }
 
 func TestHandleCompleteTask_Error(t *testing.T) {
+	ctx := context.Background()
 	m := &mockClient{err: errors.New("fail")}
-	res, err := handleCompleteTask(m, map[string]interface{}{"id": "t1"})
+	res, err := handleCompleteTask(ctx, m, map[string]interface{}{"id": "t1"})
 	if err != nil || !res.IsError {
 		t.Errorf("expected IsError=true")
 	}
@@ -355,3 +377,189 @@ func extractText(t *testing.T, res *mcp.CallToolResult) string {
 	}
 	return wrapper.Content[0].Text
 }
+
+// ---------- toolError ----------
+
+func TestToolError_TimeoutHint(t *testing.T) {
+	ctx := context.Background()
+	m := &mockClient{err: &omnifocus.TimeoutError{Operation: "list_tasks", Timeout: 30 * time.Second}}
+	res, err := handleListTasks(ctx, m, map[string]interface{}{})
+	if err != nil || !res.IsError {
+		t.Fatalf("expected IsError=true, got err=%v", err)
+	}
+	text := extractText(t, res)
+	if !strings.Contains(text, "did not respond within 30s") || !strings.Contains(text, "dialog") {
+		t.Errorf("expected timeout hint, got %q", text)
+	}
+}
+
+func TestToolError_SurfacesErrorCode(t *testing.T) {
+	ctx := context.Background()
+	m := &mockClient{err: &omnifocus.Error{Code: omnifocus.CodePermissionDenied, Operation: "complete_task", Message: "not authorized"}}
+	res, err := handleCompleteTask(ctx, m, map[string]interface{}{"id": "t1"})
+	if err != nil || !res.IsError {
+		t.Fatalf("expected IsError=true, got err=%v", err)
+	}
+	text := extractText(t, res)
+	if !strings.Contains(text, "PERMISSION_DENIED") || !strings.Contains(text, "Automation") {
+		t.Errorf("expected code and hint in message, got %q", text)
+	}
+	if res.Meta == nil || res.Meta.AdditionalFields["errorCode"] != "PERMISSION_DENIED" {
+		t.Errorf("expected errorCode in _meta, got %+v", res.Meta)
+	}
+}
+
+func TestHandleListTasks_ReportsFreshness(t *testing.T) {
+	ctx := context.Background()
+	m := &mockClient{tasks: []omnifocus.Task{{ID: "t1"}}}
+	res, err := handleListTasks(ctx, m, map[string]interface{}{})
+	if err != nil || res.IsError {
+		t.Fatalf("err=%v isError=%v", err, res.IsError)
+	}
+	if res.Meta == nil || res.Meta.AdditionalFields["stale"] != false || res.Meta.AdditionalFields["dataAgeMs"] != int64(0) {
+		t.Errorf("expected fresh data in _meta, got %+v", res.Meta)
+	}
+}
+
+// ---------- structured results ----------
+
+func TestHandleListTags_StructuredContent(t *testing.T) {
+	ctx := context.Background()
+	m := &mockClient{tags: []omnifocus.Tag{{ID: "g1", Name: "Errands", Available: true}}}
+	res, err := handleListTags(ctx, m, map[string]interface{}{})
+	if err != nil || res.IsError {
+		t.Fatalf("err=%v isError=%v", err, res.IsError)
+	}
+	list, ok := res.StructuredContent.(tagList)
+	if !ok || len(list.Tags) != 1 || list.Tags[0].ID != "g1" {
+		t.Errorf("expected the tags as structured content, got %#v", res.StructuredContent)
+	}
+	var tags []omnifocus.Tag
+	if err := json.Unmarshal([]byte(extractText(t, res)), &tags); err != nil || len(tags) != 1 {
+		t.Errorf("expected the tags as JSON text too, got %v", err)
+	}
+}
+
+// TestTools_StructuredContentMatchesOutputSchema calls each tool with an
+// output schema through a server that validates results against it
+func TestTools_StructuredContentMatchesOutputSchema(t *testing.T) {
+	due := "2026-01-31T17:00:00Z"
+	task := omnifocus.Task{ID: "t1", Name: "Write report", DueDate: &due, EstimatedMinutes: intPtr(30), Tags: []string{"Work"}, ContainingProjectID: strPtr("p1")}
+	m := &mockClient{
+		projects: []omnifocus.Project{{ID: "p1", Name: "Launch", Status: "active", FolderID: strPtr("f1"), FolderPath: []string{"Work"}}, {ID: "p2", Name: "Inbox zero"}},
+		tasks:    []omnifocus.Task{task, {ID: "t2", Name: "Inbox item"}},
+		tags:     []omnifocus.Tag{{ID: "g1", Name: "Work", Available: true}},
+		result:   &omnifocus.OperationResult{ID: "t1", Name: "Write report", Success: true, Task: &task},
+	}
+	s := server.NewMCPServer("test", "0", server.WithOutputSchemaValidation())
+	registerTools(s, m)
+
+	calls := map[string]map[string]any{
+		"list_projects":  nil,
+		"list_tasks":     nil,
+		"list_tags":      nil,
+		"create_task":    {"name": "Write report"},
+		"create_project": {"name": "Launch"},
+		"update_task":    {"id": "t1", "flagged": true},
+		"complete_task":  {"id": "t1"},
+	}
+	for name, args := range calls {
+		tool := s.GetTool(name)
+		if tool == nil || len(tool.Tool.OutputSchema.Properties) == 0 {
+			t.Errorf("%s: expected an output schema", name)
+			continue
+		}
+		params, _ := json.Marshal(map[string]any{"name": name, "arguments": args})
+		raw := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":` + string(params) + `}`
+		resp, ok := s.HandleMessage(context.Background(), json.RawMessage(raw)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Errorf("%s: unexpected response", name)
+			continue
+		}
+		res := resp.Result.(*mcp.CallToolResult)
+		if res.IsError || res.StructuredContent == nil {
+			t.Errorf("%s: expected structured content matching the schema, got %s", name, extractText(t, res))
+		}
+	}
+}
+
+// ---------- cache tools ----------
+
+type mockCacheAdmin struct {
+	stats       map[string]omnifocus.CacheStats
+	err         error
+	lastCleared *string
+}
+
+func (m *mockCacheAdmin) CacheStats() map[string]omnifocus.CacheStats { return m.stats }
+func (m *mockCacheAdmin) ClearCache(prefix string) error {
+	m.lastCleared = &prefix
+	return m.err
+}
+
+func TestHandleCacheStats(t *testing.T) {
+	ctx := context.Background()
+	m := &mockCacheAdmin{stats: map[string]omnifocus.CacheStats{"tasks": {Hits: 3, Misses: 1}}}
+	res, err := handleCacheStats(ctx, m, map[string]interface{}{})
+	if err != nil || res.IsError {
+		t.Fatalf("err=%v isError=%v", err, res.IsError)
+	}
+	var stats map[string]omnifocus.CacheStats
+	if err := json.Unmarshal([]byte(extractText(t, res)), &stats); err != nil || stats["tasks"].Hits != 3 {
+		t.Errorf("unexpected stats output %v (err=%v)", stats, err)
+	}
+}
+
+func TestHandleCacheClear(t *testing.T) {
+	ctx := context.Background()
+	m := &mockCacheAdmin{}
+	res, err := handleCacheClear(ctx, m, map[string]interface{}{"prefix": "tags:"})
+	if err != nil || res.IsError || m.lastCleared == nil || *m.lastCleared != "tags:" {
+		t.Fatalf("expected tags: to be cleared, got err=%v cleared=%v", err, m.lastCleared)
+	}
+
+	res, _ = handleCacheClear(ctx, m, map[string]interface{}{})
+	if *m.lastCleared != "" || !strings.Contains(extractText(t, res), "all") {
+		t.Errorf("expected a full clear, got %q", extractText(t, res))
+	}
+
+	m.err = omnifocus.ErrInvalidArgument
+	res, _ = handleCacheClear(ctx, m, map[string]interface{}{"prefix": "folders:"})
+	if !res.IsError || !strings.Contains(extractText(t, res), "INVALID_ARGUMENT") {
+		t.Errorf("expected INVALID_ARGUMENT error, got %q", extractText(t, res))
+	}
+}
+
+type mockQuerier struct {
+	query  string
+	result *omnifocus.QueryResult
+	err    error
+}
+
+func (m *mockQuerier) Query(ctx context.Context, query string, args ...any) (*omnifocus.QueryResult, error) {
+	m.query = query
+	return m.result, m.err
+}
+
+func TestHandleQueryDatabase(t *testing.T) {
+	ctx := context.Background()
+	m := &mockQuerier{result: &omnifocus.QueryResult{Columns: []string{"name"}, Rows: [][]any{{"Chase invoice"}}}}
+	res, err := handleQueryDatabase(ctx, m, map[string]interface{}{"sql": "SELECT name FROM tasks"})
+	if err != nil || res.IsError || m.query != "SELECT name FROM tasks" {
+		t.Fatalf("err=%v query=%q", err, m.query)
+	}
+	if !strings.Contains(extractText(t, res), "Chase invoice") {
+		t.Errorf("expected the rows in the result, got %q", extractText(t, res))
+	}
+
+	res, _ = handleQueryDatabase(ctx, m, map[string]interface{}{})
+	if !res.IsError || !strings.Contains(extractText(t, res), "INVALID_ARGUMENT") {
+		t.Errorf("expected missing sql to be rejected, got %q", extractText(t, res))
+	}
+
+	m.err = omnifocus.ErrInvalidArgument
+	res, _ = handleQueryDatabase(ctx, m, map[string]interface{}{"sql": "DELETE FROM tasks"})
+	if !res.IsError || !strings.Contains(extractText(t, res), "INVALID_ARGUMENT") {
+		t.Errorf("expected INVALID_ARGUMENT error, got %q", extractText(t, res))
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressMiddleware sends the progress of a tool call's OmniFocus
+// operations to the client as progress notifications, when the client asked
+// for them by giving the call a progress token. Cancelling the call stops
+// the operation: mcp-go cancels the handler's context, and the client kills
+// the running script.
+func progressMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		s := server.ServerFromContext(ctx)
+		if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil || s == nil {
+			return next(ctx, request)
+		}
+		token := request.Params.Meta.ProgressToken
+
+		// A read shared with other callers can outlive the call, and no
+		// progress may be sent for a call that has finished
+		var mu sync.Mutex
+		done := false
+		defer func() {
+			mu.Lock()
+			done = true
+			mu.Unlock()
+		}()
+
+		progressCtx := omnifocus.WithProgress(ctx, func(progress int, message string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if done {
+				return
+			}
+			err := s.SendNotificationToClient(ctx, string(mcp.MethodNotificationProgress), map[string]any{
+				"progressToken": token,
+				"progress":      progress,
+				"message":       message,
+			})
+			if err != nil {
+				log.Printf("Failed to send progress for %s: %v", request.Params.Name, err)
+			}
+		})
+		return next(progressCtx, request)
+	}
+}
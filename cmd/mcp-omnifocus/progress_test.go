@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressClient reports two phases from ListTasks and keeps the context,
+// so the test can report again after the call has finished
+type progressClient struct {
+	mockClient
+	ctx context.Context
+}
+
+func (c *progressClient) ListTasks(ctx context.Context, projectID string) ([]omnifocus.Task, error) {
+	c.ctx = ctx
+	omnifocus.ReportProgress(ctx, "Launching osascript for list_tasks")
+	omnifocus.ReportProgress(ctx, "Parsing tasks (%d bytes)", 42)
+	return nil, nil
+}
+
+// callWithProgress calls list_tasks through a server with the progress
+// middleware, setting the progress token if it is not nil, and returns the
+// session, which has buffered the notifications sent
+func callWithProgress(t *testing.T, client *progressClient, token any) *testSession {
+	t.Helper()
+	s := server.NewMCPServer("test", "0", server.WithToolHandlerMiddleware(progressMiddleware))
+	registerTools(s, client)
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	if err := s.RegisterSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]any{"name": "list_tasks"}
+	if token != nil {
+		params["_meta"] = map[string]any{"progressToken": token}
+	}
+	raw, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": params})
+	resp := s.HandleMessage(s.WithContext(context.Background(), session), raw)
+	if res, ok := resp.(mcp.JSONRPCResponse); !ok || res.Result.(*mcp.CallToolResult).IsError {
+		t.Fatalf("unexpected response %#v", resp)
+	}
+	return session
+}
+
+// drain returns the notifications the session has received since it was
+// last drained
+func drain(session *testSession) []mcp.JSONRPCNotification {
+	var got []mcp.JSONRPCNotification
+	for {
+		select {
+		case n := <-session.notifications:
+			got = append(got, n)
+		default:
+			return got
+		}
+	}
+}
+
+func TestProgressMiddleware_SendsNotifications(t *testing.T) {
+	client := &progressClient{}
+	session := callWithProgress(t, client, "tok-1")
+	got := drain(session)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 progress notifications, got %v", got)
+	}
+	for i, want := range []string{"Launching osascript for list_tasks", "Parsing tasks (42 bytes)"} {
+		fields := got[i].Params.AdditionalFields
+		if got[i].Method != "notifications/progress" || fields["progressToken"] != "tok-1" || fields["progress"] != i+1 || fields["message"] != want {
+			t.Errorf("unexpected notification %d: %s %v", i, got[i].Method, fields)
+		}
+	}
+
+	// Nothing may be sent for a call that has finished
+	omnifocus.ReportProgress(client.ctx, "Late")
+	if late := drain(session); len(late) != 0 {
+		t.Errorf("expected no progress after the call finished, got %v", late)
+	}
+}
+
+func TestProgressMiddleware_OnlyWithToken(t *testing.T) {
+	if got := drain(callWithProgress(t, &progressClient{}, nil)); len(got) != 0 {
+		t.Errorf("expected no notifications without a progress token, got %v", got)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// gtdPrompt is a prompt and the function that writes its text from live
+// OmniFocus data. now is in the user's time zone.
+type gtdPrompt struct {
+	prompt mcp.Prompt
+	render func(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]string, now time.Time) (string, error)
+}
+
+// gtdPrompts are the workflows offered in the client's prompt picker
+var gtdPrompts = []gtdPrompt{
+	{
+		prompt: mcp.NewPrompt("weekly_review",
+			mcp.WithPromptDescription("Run a GTD weekly review of your projects, with their remaining and overdue tasks"),
+			mcp.WithArgument("folder",
+				mcp.ArgumentDescription("Folder name or ID to review (default: every project)"),
+			),
+		),
+		render: renderWeeklyReview,
+	},
+	{
+		prompt: mcp.NewPrompt("daily_plan",
+			mcp.WithPromptDescription("Plan today from overdue, due and flagged tasks"),
+			mcp.WithArgument("hours",
+				mcp.ArgumentDescription("Hours available for tasks today"),
+				mcp.RequiredArgument(),
+			),
+		),
+		render: renderDailyPlan,
+	},
+	{
+		prompt: mcp.NewPrompt("inbox_triage",
+			mcp.WithPromptDescription("Process the inbox to zero, deciding what each item is and where it goes"),
+		),
+		render: renderInboxTriage,
+	},
+	{
+		prompt: mcp.NewPrompt("project_kickoff",
+			mcp.WithPromptDescription("Plan a new project: its outcome, next actions and tags"),
+			mcp.WithArgument("name",
+				mcp.ArgumentDescription("Name of the new project"),
+				mcp.RequiredArgument(),
+			),
+			mcp.WithArgument("outcome",
+				mcp.ArgumentDescription("What done looks like, if already known"),
+			),
+		),
+		render: renderProjectKickoff,
+	},
+}
+
+// registerPrompts adds the GTD workflow prompts. Their data is read through
+// client, so it honours the scope; loc is the time zone for dates.
+func registerPrompts(s *server.MCPServer, client omnifocus.OmniFocusClient, loc *time.Location) {
+	for _, p := range gtdPrompts {
+		s.AddPrompt(p.prompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			text, err := p.render(ctx, client, request.Params.Arguments, time.Now().In(loc))
+			if err != nil {
+				return nil, err
+			}
+			return mcp.NewGetPromptResult(p.prompt.Description, []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+			}), nil
+		})
+	}
+}
+
+func renderWeeklyReview(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]string, now time.Time) (string, error) {
+	folder := strings.TrimSpace(args["folder"])
+	projects, err := client.ListProjects(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list projects: %w", err)
+	}
+	projects = filter(projects, func(p omnifocus.Project) bool {
+		return !p.Completed && (folder == "" || inFolder(p, folder))
+	})
+	if folder != "" && len(projects) == 0 {
+		return "", fmt.Errorf("no open projects in folder %q: %w", folder, omnifocus.ErrNotFound)
+	}
+	tasks, err := client.ListTasks(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks: %w", err)
+	}
+	byProject := remainingByProject(tasks)
+
+	var b strings.Builder
+	scope := "all my projects"
+	if folder != "" {
+		scope = fmt.Sprintf("the projects in my %q folder", folder)
+	}
+	fmt.Fprintf(&b, "Walk me through a GTD weekly review of %s. Today is %s.\n\n", scope, now.Format("Monday 2 January 2006"))
+	b.WriteString("For each project, check that the outcome is still wanted and that it has a clear next action. ")
+	b.WriteString("Call out projects with no remaining tasks, overdue tasks and anything that should be put on hold or dropped. ")
+	b.WriteString("Ask me before changing anything.\n\n## Projects\n")
+	for _, p := range projects {
+		remaining := byProject[p.ID]
+		fmt.Fprintf(&b, "\n### %s (id %s)\nStatus: %s; %d remaining tasks\n", p.Name, p.ID, p.Status, len(remaining))
+		if len(remaining) == 0 {
+			b.WriteString("No next action\n")
+		}
+		for _, t := range remaining {
+			b.WriteString(formatTask(t, now))
+		}
+	}
+	return b.String(), nil
+}
+
+func renderDailyPlan(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]string, now time.Time) (string, error) {
+	hours, err := strconv.ParseFloat(strings.TrimSpace(args["hours"]), 64)
+	if err != nil || hours <= 0 {
+		return "", fmt.Errorf("hours must be a positive number, got %q: %w", args["hours"], omnifocus.ErrInvalidArgument)
+	}
+	tasks, err := client.ListTasks(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	// Candidates are tasks due by the end of today, then flagged tasks
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	var due, flagged []omnifocus.Task
+	for _, t := range tasks {
+		switch {
+		case t.Completed:
+		case dueBefore(t, endOfDay):
+			due = append(due, t)
+		case t.Flagged:
+			flagged = append(flagged, t)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b omnifocus.Task) int { return strings.Compare(*a.DueDate, *b.DueDate) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Help me plan today, %s. I have %s hours for tasks.\n\n", now.Format("Monday 2 January 2006"), strconv.FormatFloat(hours, 'f', -1, 64))
+	b.WriteString("Pick what fits, using the estimates where they exist, with overdue and due tasks first. ")
+	b.WriteString("Order the plan, say what does not fit and suggest new due dates for it. Ask me before changing anything.\n\n")
+	writeTaskSection(&b, "Overdue or due today", due, now)
+	writeTaskSection(&b, "Flagged", flagged, now)
+	return b.String(), nil
+}
+
+func renderInboxTriage(ctx context.Context, client omnifocus.OmniFocusClient, _ map[string]string, now time.Time) (string, error) {
+	tasks, err := client.ListTasks(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks: %w", err)
+	}
+	inbox := filter(tasks, func(t omnifocus.Task) bool {
+		return !t.Completed && t.ContainingProjectID == nil
+	})
+	projects, tags, err := listProjectsAndTags(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Help me get my OmniFocus inbox to zero. Take the items one at a time. ")
+	b.WriteString("For each, decide whether it is actionable; if so, whether to do it now (under two minutes), defer it with a due date or file it in a project with tags. ")
+	b.WriteString("Suggest dropping anything that is no longer relevant. Ask me before changing anything.\n\n")
+	writeTaskSection(&b, "Inbox", inbox, now)
+	writeProjectsAndTags(&b, projects, tags)
+	return b.String(), nil
+}
+
+func renderProjectKickoff(ctx context.Context, client omnifocus.OmniFocusClient, args map[string]string, _ time.Time) (string, error) {
+	name := strings.TrimSpace(args["name"])
+	if name == "" {
+		return "", fmt.Errorf("name is required: %w", omnifocus.ErrInvalidArgument)
+	}
+	projects, tags, err := listProjectsAndTags(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Help me kick off a new project, %q.\n\n", name)
+	if outcome := strings.TrimSpace(args["outcome"]); outcome != "" {
+		fmt.Fprintf(&b, "The outcome I want: %s\n\n", outcome)
+	} else {
+		b.WriteString("Start by helping me define the outcome: what does done look like?\n\n")
+	}
+	b.WriteString("Then brainstorm what is involved, and propose the first next actions with estimates and tags. ")
+	b.WriteString("Check that it does not duplicate one of my existing projects. ")
+	b.WriteString("Once I agree, create the project with create_project and its tasks with create_task.\n\n")
+	writeProjectsAndTags(&b, projects, tags)
+	return b.String(), nil
+}
+
+// inFolder reports whether a project is in folder, given by ID or by the
+// name of any enclosing folder
+func inFolder(p omnifocus.Project, folder string) bool {
+	if p.FolderID != nil && *p.FolderID == folder {
+		return true
+	}
+	return slices.ContainsFunc(p.FolderPath, func(name string) bool { return strings.EqualFold(name, folder) })
+}
+
+// remainingByProject groups the incomplete tasks by project, leaving out
+// each project's own root task
+func remainingByProject(tasks []omnifocus.Task) map[string][]omnifocus.Task {
+	byProject := make(map[string][]omnifocus.Task)
+	for _, t := range tasks {
+		if t.Completed || t.ContainingProjectID == nil || *t.ContainingProjectID == t.ID {
+			continue
+		}
+		byProject[*t.ContainingProjectID] = append(byProject[*t.ContainingProjectID], t)
+	}
+	return byProject
+}
+
+// dueBefore reports whether a task is due before t
+func dueBefore(task omnifocus.Task, t time.Time) bool {
+	if task.DueDate == nil {
+		return false
+	}
+	due, err := time.Parse(time.RFC3339Nano, *task.DueDate)
+	return err == nil && due.Before(t)
+}
+
+func listProjectsAndTags(ctx context.Context, client omnifocus.OmniFocusClient) ([]omnifocus.Project, []omnifocus.Tag, error) {
+	projects, err := client.ListProjects(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	tags, err := client.ListTags(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return filter(projects, func(p omnifocus.Project) bool { return !p.Completed }), tags, nil
+}
+
+// filter returns the items for which keep is true, leaving items untouched
+func filter[T any](items []T, keep func(T) bool) []T {
+	var kept []T
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// formatTask formats a task as a Markdown list item with its ID, due date,
+// flag, estimate and tags
+func formatTask(t omnifocus.Task, now time.Time) string {
+	details := []string{"id " + t.ID}
+	if t.DueDate != nil {
+		if due, err := time.Parse(time.RFC3339Nano, *t.DueDate); err == nil {
+			details = append(details, "due "+due.In(now.Location()).Format("Mon 2 Jan 15:04"))
+			if due.Before(now) {
+				details = append(details, "overdue")
+			}
+		}
+	}
+	if t.Flagged {
+		details = append(details, "flagged")
+	}
+	if t.EstimatedMinutes != nil {
+		details = append(details, fmt.Sprintf("%d min", *t.EstimatedMinutes))
+	}
+	if len(t.Tags) > 0 {
+		details = append(details, "tags: "+strings.Join(t.Tags, ", "))
+	}
+	return fmt.Sprintf("- %s (%s)\n", t.Name, strings.Join(details, "; "))
+}
+
+func writeTaskSection(b *strings.Builder, title string, tasks []omnifocus.Task, now time.Time) {
+	fmt.Fprintf(b, "## %s\n", title)
+	if len(tasks) == 0 {
+		b.WriteString("None\n")
+	}
+	for _, t := range tasks {
+		b.WriteString(formatTask(t, now))
+	}
+	b.WriteString("\n")
+}
+
+func writeProjectsAndTags(b *strings.Builder, projects []omnifocus.Project, tags []omnifocus.Tag) {
+	b.WriteString("## Open projects\n")
+	for _, p := range projects {
+		fmt.Fprintf(b, "- %s (id %s", p.Name, p.ID)
+		if len(p.FolderPath) > 0 {
+			fmt.Fprintf(b, "; folder %s", strings.Join(p.FolderPath, " / "))
+		}
+		b.WriteString(")\n")
+	}
+	b.WriteString("\n## Tags\n")
+	for _, t := range tags {
+		fmt.Fprintf(b, "- %s\n", t.Name)
+	}
+}
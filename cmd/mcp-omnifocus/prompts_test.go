@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// getPrompt sends a prompts/get request through an MCP server with the
+// prompts registered, returning the prompt text or the error message
+func getPrompt(t *testing.T, client omnifocus.OmniFocusClient, name string, args map[string]string) (text string, errMsg string) {
+	t.Helper()
+	s := server.NewMCPServer("test", "0")
+	registerPrompts(s, client, time.UTC)
+
+	params, _ := json.Marshal(map[string]any{"name": name, "arguments": args})
+	raw := `{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":` + string(params) + `}`
+	switch resp := s.HandleMessage(context.Background(), json.RawMessage(raw)).(type) {
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.GetPromptResult)
+		if !ok || len(result.Messages) != 1 {
+			t.Fatalf("unexpected result %#v", resp.Result)
+		}
+		return result.Messages[0].Content.(mcp.TextContent).Text, ""
+	case mcp.JSONRPCError:
+		return "", resp.Error.Message
+	default:
+		t.Fatalf("unexpected response %#v", resp)
+	}
+	return "", ""
+}
+
+func TestPrompts_Listed(t *testing.T) {
+	s := server.NewMCPServer("test", "0")
+	registerPrompts(s, &mockClient{}, time.UTC)
+
+	resp := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`))
+	result, ok := resp.(mcp.JSONRPCResponse).Result.(mcp.ListPromptsResult)
+	if !ok {
+		t.Fatalf("unexpected response %#v", resp)
+	}
+	names := make(map[string]bool)
+	for _, p := range result.Prompts {
+		names[p.Name] = true
+	}
+	for _, want := range []string{"weekly_review", "daily_plan", "inbox_triage", "project_kickoff"} {
+		if !names[want] {
+			t.Errorf("expected prompt %s, got %v", want, names)
+		}
+	}
+}
+
+func TestPrompts_WeeklyReviewForFolder(t *testing.T) {
+	m := &mockClient{
+		projects: []omnifocus.Project{
+			{ID: "p1", Name: "Launch", Status: "active", FolderPath: []string{"Work"}},
+			{ID: "p2", Name: "Garden", Status: "active", FolderPath: []string{"Home"}},
+			{ID: "p3", Name: "Offsite", Status: "active", FolderPath: []string{"Work"}},
+		},
+		tasks: []omnifocus.Task{
+			{ID: "p1", Name: "Launch", ContainingProjectID: strPtr("p1")},
+			{ID: "t1", Name: "Write announcement", ContainingProjectID: strPtr("p1")},
+			{ID: "t2", Name: "Book venue", Completed: true, ContainingProjectID: strPtr("p3")},
+		},
+	}
+
+	text, errMsg := getPrompt(t, m, "weekly_review", map[string]string{"folder": "work"})
+	if errMsg != "" {
+		t.Fatalf("unexpected error %q", errMsg)
+	}
+	for _, want := range []string{"Launch (id p1)", "Write announcement (id t1)", "### Offsite (id p3)\nStatus: active; 0 remaining tasks\nNo next action"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in prompt:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Garden") || strings.Contains(text, "Book venue") {
+		t.Errorf("expected other folders and completed tasks left out:\n%s", text)
+	}
+
+	if _, errMsg := getPrompt(t, m, "weekly_review", map[string]string{"folder": "Errands"}); !strings.Contains(errMsg, "not found") {
+		t.Errorf("expected a not found error for an empty folder, got %q", errMsg)
+	}
+}
+
+func TestPrompts_DailyPlan(t *testing.T) {
+	yesterday := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	nextWeek := time.Now().Add(7 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	m := &mockClient{
+		tasks: []omnifocus.Task{
+			{ID: "t1", Name: "File taxes", DueDate: &yesterday},
+			{ID: "t2", Name: "Plan trip", DueDate: &nextWeek},
+			{ID: "t3", Name: "Call dentist", Flagged: true, EstimatedMinutes: intPtr(10)},
+			{ID: "t4", Name: "Old chore", DueDate: &yesterday, Completed: true},
+		},
+	}
+
+	text, errMsg := getPrompt(t, m, "daily_plan", map[string]string{"hours": "2.5"})
+	if errMsg != "" {
+		t.Fatalf("unexpected error %q", errMsg)
+	}
+	for _, want := range []string{"I have 2.5 hours", "File taxes (id t1; due", "overdue", "Call dentist (id t3; flagged; 10 min)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in prompt:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Plan trip") || strings.Contains(text, "Old chore") {
+		t.Errorf("expected later and completed tasks left out:\n%s", text)
+	}
+
+	for _, hours := range []string{"", "soon", "-1"} {
+		if _, errMsg := getPrompt(t, m, "daily_plan", map[string]string{"hours": hours}); !strings.Contains(errMsg, "hours must be a positive number") {
+			t.Errorf("hours %q: expected an invalid argument error, got %q", hours, errMsg)
+		}
+	}
+}
+
+func TestPrompts_InboxTriage(t *testing.T) {
+	m := &mockClient{
+		projects: []omnifocus.Project{{ID: "p1", Name: "Launch", FolderPath: []string{"Work"}}},
+		tasks: []omnifocus.Task{
+			{ID: "t1", Name: "Idea for blog post"},
+			{ID: "t2", Name: "Done already", Completed: true},
+			{ID: "t3", Name: "Write announcement", ContainingProjectID: strPtr("p1")},
+		},
+		tags: []omnifocus.Tag{{ID: "g1", Name: "Errands"}},
+	}
+
+	text, errMsg := getPrompt(t, m, "inbox_triage", nil)
+	if errMsg != "" {
+		t.Fatalf("unexpected error %q", errMsg)
+	}
+	for _, want := range []string{"Idea for blog post (id t1)", "Launch (id p1; folder Work)", "- Errands"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in prompt:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Done already") || strings.Contains(text, "Write announcement") {
+		t.Errorf("expected only open inbox tasks:\n%s", text)
+	}
+}
+
+func TestPrompts_ProjectKickoff(t *testing.T) {
+	m := &mockClient{
+		projects: []omnifocus.Project{{ID: "p1", Name: "Launch"}},
+		tags:     []omnifocus.Tag{{ID: "g1", Name: "Errands"}},
+	}
+
+	text, errMsg := getPrompt(t, m, "project_kickoff", map[string]string{"name": "Move house", "outcome": "Living in the new flat"})
+	if errMsg != "" {
+		t.Fatalf("unexpected error %q", errMsg)
+	}
+	for _, want := range []string{`"Move house"`, "The outcome I want: Living in the new flat", "Launch (id p1)", "- Errands", "create_project"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in prompt:\n%s", want, text)
+		}
+	}
+
+	if _, errMsg := getPrompt(t, m, "project_kickoff", map[string]string{"name": " "}); !strings.Contains(errMsg, "name is required") {
+		t.Errorf("expected an invalid argument error, got %q", errMsg)
+	}
+}
+
+func strPtr(s string) *string { return &s }
+func intPtr(n int) *int       { return &n }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Resource URIs. Single items are addressed by their OmniFocus ID.
+const (
+	projectsURI        = "omnifocus://projects"
+	tagsURI            = "omnifocus://tags"
+	projectURITemplate = "omnifocus://project/{id}"
+	taskURITemplate    = "omnifocus://task/{id}"
+)
+
+// projectResource is the content of omnifocus://project/{id}
+type projectResource struct {
+	omnifocus.Project
+	Tasks []omnifocus.Task `json:"tasks"`
+}
+
+// registerResources exposes OmniFocus data as resources, read through the
+// same client, cache and scope as the tools
+func registerResources(s *server.MCPServer, client omnifocus.OmniFocusClient) {
+	s.AddResource(mcp.NewResource(projectsURI, "Projects",
+		mcp.WithResourceDescription("All projects in OmniFocus"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return readProjects(ctx, client, request)
+	})
+
+	s.AddResource(mcp.NewResource(tagsURI, "Tags",
+		mcp.WithResourceDescription("All tags in OmniFocus"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return readTags(ctx, client, request)
+	})
+
+	s.AddResourceTemplate(mcp.NewResourceTemplate(projectURITemplate, "Project",
+		mcp.WithTemplateDescription("A project and its tasks"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return readProject(ctx, client, request)
+	})
+
+	s.AddResourceTemplate(mcp.NewResourceTemplate(taskURITemplate, "Task",
+		mcp.WithTemplateDescription("A single task"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return readTask(ctx, client, request)
+	})
+}
+
+func readProjects(ctx context.Context, client omnifocus.OmniFocusClient, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	projects, err := client.ListProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	return jsonResource(request.Params.URI, projects), nil
+}
+
+func readTags(ctx context.Context, client omnifocus.OmniFocusClient, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	tags, err := client.ListTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return jsonResource(request.Params.URI, tags), nil
+}
+
+func readProject(ctx context.Context, client omnifocus.OmniFocusClient, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := resourceID(request)
+	projects, err := client.ListProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	for _, p := range projects {
+		if p.ID != id {
+			continue
+		}
+		tasks, err := client.ListTasks(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tasks: %w", err)
+		}
+		return jsonResource(request.Params.URI, projectResource{Project: p, Tasks: tasks}), nil
+	}
+	return nil, fmt.Errorf("project %s: %w", id, omnifocus.ErrNotFound)
+}
+
+func readTask(ctx context.Context, client omnifocus.OmniFocusClient, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := resourceID(request)
+	tasks, err := client.ListTasks(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	for _, t := range tasks {
+		if t.ID == id {
+			return jsonResource(request.Params.URI, t), nil
+		}
+	}
+	return nil, fmt.Errorf("task %s: %w", id, omnifocus.ErrNotFound)
+}
+
+// resourceID returns the {id} matched from a resource template. mcp-go
+// passes template variables as string slices.
+func resourceID(request mcp.ReadResourceRequest) string {
+	switch id := request.Params.Arguments["id"].(type) {
+	case string:
+		return id
+	case []string:
+		if len(id) > 0 {
+			return id[0]
+		}
+	}
+	return ""
+}
+
+// jsonResource renders v as the JSON contents of the resource at uri
+func jsonResource(uri string, v any) []mcp.ResourceContents {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}
+}
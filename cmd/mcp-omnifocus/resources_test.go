@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// readResource sends a resources/read request through an MCP server with
+// the resources registered, returning the text contents or the error message
+func readResource(t *testing.T, client omnifocus.OmniFocusClient, uri string) (text string, errMsg string) {
+	t.Helper()
+	s := server.NewMCPServer("test", "0")
+	registerResources(s, client)
+
+	raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, uri)
+	switch resp := s.HandleMessage(context.Background(), json.RawMessage(raw)).(type) {
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.ReadResourceResult)
+		if !ok || len(result.Contents) != 1 {
+			t.Fatalf("unexpected result %#v", resp.Result)
+		}
+		return result.Contents[0].(mcp.TextResourceContents).Text, ""
+	case mcp.JSONRPCError:
+		return "", resp.Error.Message
+	default:
+		t.Fatalf("unexpected response %#v", resp)
+	}
+	return "", ""
+}
+
+func TestResources_Lists(t *testing.T) {
+	m := &mockClient{
+		projects: []omnifocus.Project{{ID: "p1", Name: "Alpha"}},
+		tags:     []omnifocus.Tag{{ID: "g1", Name: "Waiting"}},
+	}
+
+	if text, errMsg := readResource(t, m, "omnifocus://projects"); !strings.Contains(text, "Alpha") {
+		t.Errorf("expected projects, got %q (error %q)", text, errMsg)
+	}
+	if text, errMsg := readResource(t, m, "omnifocus://tags"); !strings.Contains(text, "Waiting") {
+		t.Errorf("expected tags, got %q (error %q)", text, errMsg)
+	}
+}
+
+func TestResources_ProjectWithTasks(t *testing.T) {
+	m := &mockClient{
+		projects: []omnifocus.Project{{ID: "p1", Name: "Alpha"}},
+		tasks:    []omnifocus.Task{{ID: "t1", Name: "Write spec"}},
+	}
+
+	text, errMsg := readResource(t, m, "omnifocus://project/p1")
+	var got projectResource
+	if err := json.Unmarshal([]byte(text), &got); err != nil {
+		t.Fatalf("bad contents %q (error %q): %v", text, errMsg, err)
+	}
+	if got.ID != "p1" || got.Name != "Alpha" || len(got.Tasks) != 1 || got.Tasks[0].ID != "t1" {
+		t.Errorf("expected project p1 with its task, got %+v", got)
+	}
+
+	if _, errMsg := readResource(t, m, "omnifocus://project/missing"); !strings.Contains(errMsg, "not found") {
+		t.Errorf("expected a not found error, got %q", errMsg)
+	}
+}
+
+func TestResources_Task(t *testing.T) {
+	m := &mockClient{tasks: []omnifocus.Task{{ID: "t1", Name: "Write spec"}, {ID: "t2", Name: "Review"}}}
+
+	text, errMsg := readResource(t, m, "omnifocus://task/t2")
+	if !strings.Contains(text, "Review") || strings.Contains(text, "Write spec") {
+		t.Errorf("expected only task t2, got %q (error %q)", text, errMsg)
+	}
+	if _, errMsg := readResource(t, m, "omnifocus://task/t9"); !strings.Contains(errMsg, "not found") {
+		t.Errorf("expected a not found error, got %q", errMsg)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Prefixes of the single item URIs, followed by the item's ID
+var (
+	projectURIPrefix = strings.TrimSuffix(projectURITemplate, "{id}")
+	taskURIPrefix    = strings.TrimSuffix(taskURITemplate, "{id}")
+)
+
+// notifier sends notifications to MCP clients; *server.MCPServer implements
+// it
+type notifier interface {
+	SendNotificationToSpecificClient(sessionID, method string, params map[string]any) error
+	SendNotificationToAllClients(method string, params map[string]any)
+}
+
+// subscriptions records the resources each session has subscribed to and
+// tells it when they change. mcp-go accepts resources/subscribe but its
+// stdio session keeps no record of it, so the server tracks subscriptions
+// through hooks.
+type subscriptions struct {
+	mu sync.Mutex
+	// sessions maps session IDs to their subscribed URIs
+	sessions map[string]map[string]bool
+	notifier notifier
+	// visible, if set, hides resources outside the scope from
+	// notifications; see restrict
+	visible func(ctx context.Context, uri string) bool
+	// filtering counts publishes still checking visibility
+	filtering sync.WaitGroup
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{sessions: make(map[string]map[string]bool)}
+}
+
+// hooks returns the server hooks that keep the subscriptions current.
+// Notifications are only sent once attach has been called.
+func (s *subscriptions) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddAfterSubscribe(func(ctx context.Context, _ any, request *mcp.SubscribeRequest, _ *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			s.subscribe(session.SessionID(), request.Params.URI)
+		}
+	})
+	hooks.AddAfterUnsubscribe(func(ctx context.Context, _ any, request *mcp.UnsubscribeRequest, _ *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			s.unsubscribe(session.SessionID(), request.Params.URI)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		s.drop(session.SessionID())
+	})
+	return hooks
+}
+
+// attach sets where notifications are sent
+func (s *subscriptions) attach(n notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notifier = n
+}
+
+// restrict limits notifications to the resources that c can see, so a
+// session subscribed to a task or project outside the scope learns nothing
+// about it
+func (s *subscriptions) restrict(c *omnifocus.ScopedClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.visible = func(ctx context.Context, uri string) bool {
+		if id, ok := strings.CutPrefix(uri, projectURIPrefix); ok {
+			return c.ProjectVisible(ctx, id)
+		}
+		if id, ok := strings.CutPrefix(uri, taskURIPrefix); ok {
+			return c.TaskVisible(ctx, id)
+		}
+		// The lists are filtered by the scope when they are read
+		return true
+	}
+}
+
+func (s *subscriptions) subscribe(sessionID, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions[sessionID] == nil {
+		s.sessions[sessionID] = make(map[string]bool)
+	}
+	s.sessions[sessionID][uri] = true
+}
+
+func (s *subscriptions) unsubscribe(sessionID, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions[sessionID], uri)
+	if len(s.sessions[sessionID]) == 0 {
+		delete(s.sessions, sessionID)
+	}
+}
+
+// drop forgets a closed session's subscriptions
+func (s *subscriptions) drop(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+}
+
+// publish sends notifications/resources/updated for every subscribed
+// resource that ch affects, and notifications/resources/list_changed to
+// every session if items were created or deleted. It is registered with
+// Client.OnChange, so it must not block: checking the scope may need to
+// read from OmniFocus, so restricted notifications are sent in the
+// background, and mcp-go drops notifications for sessions that are not
+// keeping up.
+func (s *subscriptions) publish(ch omnifocus.Change) {
+	s.mu.Lock()
+	n := s.notifier
+	visible := s.visible
+	updated := make(map[string][]string)
+	for sessionID, uris := range s.sessions {
+		for uri := range uris {
+			if affects(ch, uri) {
+				updated[sessionID] = append(updated[sessionID], uri)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if n == nil {
+		return
+	}
+	if visible == nil {
+		s.send(n, updated, ch.ListChanged)
+		return
+	}
+	s.filtering.Add(1)
+	go func() {
+		defer s.filtering.Done()
+		s.send(n, filterVisible(updated, visible), ch.ListChanged)
+	}()
+}
+
+// filterVisible drops the URIs that visible hides, checking each URI once
+func filterVisible(updated map[string][]string, visible func(context.Context, string) bool) map[string][]string {
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	filtered := make(map[string][]string)
+	for sessionID, uris := range updated {
+		for _, uri := range uris {
+			ok, checked := seen[uri]
+			if !checked {
+				ok = visible(ctx, uri)
+				seen[uri] = ok
+			}
+			if ok {
+				filtered[sessionID] = append(filtered[sessionID], uri)
+			}
+		}
+	}
+	return filtered
+}
+
+// send sends the updates and, if listChanged, list_changed to every session
+func (s *subscriptions) send(n notifier, updated map[string][]string, listChanged bool) {
+	for sessionID, uris := range updated {
+		for _, uri := range uris {
+			err := n.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+			if err != nil {
+				log.Printf("Failed to notify session %s of a change to %s: %v", sessionID, uri, err)
+			}
+		}
+	}
+	if listChanged {
+		n.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+	}
+}
+
+// affects reports whether ch may have changed the resource at uri
+func affects(ch omnifocus.Change, uri string) bool {
+	if uri == projectsURI {
+		return ch.Projects || len(ch.ProjectIDs) > 0
+	}
+	if uri == tagsURI {
+		return ch.Tags
+	}
+	// Project resources list their tasks, and tasks carry tag names
+	if id, ok := strings.CutPrefix(uri, projectURIPrefix); ok {
+		return ch.Projects || ch.Tasks || ch.Tags || slices.Contains(ch.ProjectIDs, id)
+	}
+	if id, ok := strings.CutPrefix(uri, taskURIPrefix); ok {
+		return ch.Tasks || ch.Tags || slices.Contains(ch.TaskIDs, id)
+	}
+	return false
+}
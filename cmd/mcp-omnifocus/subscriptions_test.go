@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/conall/mcp-omnifocus/internal/omnifocus"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testSession is an initialized client session that buffers notifications
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()       {}
+func (s *testSession) Initialized() bool { return true }
+func (s *testSession) SessionID() string { return "test-session" }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// received drains the notifications sent so far as "method uri" strings
+func (s *testSession) received() map[string]bool {
+	got := make(map[string]bool)
+	for {
+		select {
+		case n := <-s.notifications:
+			got[fmt.Sprintf("%s %v", n.Method, n.Params.AdditionalFields["uri"])] = true
+		default:
+			return got
+		}
+	}
+}
+
+// newSubscribedServer returns a server with the resources registered and a
+// session subscribed to uris
+func newSubscribedServer(t *testing.T, uris ...string) (*subscriptions, *server.MCPServer, *testSession) {
+	t.Helper()
+	subs := newSubscriptions()
+	s := server.NewMCPServer("test", "0",
+		server.WithResourceCapabilities(true, true),
+		server.WithHooks(subs.hooks()),
+	)
+	subs.attach(s)
+	registerResources(s, &mockClient{})
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	if err := s.RegisterSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range uris {
+		sendResourceRequest(t, s, session, "resources/subscribe", uri)
+	}
+	return subs, s, session
+}
+
+func sendResourceRequest(t *testing.T, s *server.MCPServer, session *testSession, method, uri string) {
+	t.Helper()
+	ctx := s.WithContext(context.Background(), session)
+	raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":{"uri":%q}}`, method, uri)
+	if resp, ok := s.HandleMessage(ctx, json.RawMessage(raw)).(mcp.JSONRPCError); ok {
+		t.Fatalf("%s failed: %s", method, resp.Error.Message)
+	}
+}
+
+func TestSubscriptions_NotifiesSubscribedResources(t *testing.T) {
+	subs, _, session := newSubscribedServer(t, "omnifocus://task/t1", "omnifocus://project/p1", "omnifocus://tags")
+
+	subs.publish(omnifocus.Change{TaskIDs: []string{"t1"}, ProjectIDs: []string{"p2"}})
+	got := session.received()
+	if len(got) != 1 || !got["notifications/resources/updated omnifocus://task/t1"] {
+		t.Errorf("expected only the task to be updated, got %v", got)
+	}
+
+	subs.publish(omnifocus.Change{Tags: true, ListChanged: true})
+	got = session.received()
+	for _, want := range []string{
+		"notifications/resources/updated omnifocus://tags",
+		// Tasks carry tag names
+		"notifications/resources/updated omnifocus://task/t1",
+		"notifications/resources/updated omnifocus://project/p1",
+		"notifications/resources/list_changed <nil>",
+	} {
+		if !got[want] {
+			t.Errorf("expected %q, got %v", want, got)
+		}
+	}
+}
+
+func TestSubscriptions_RestrictedToScope(t *testing.T) {
+	subs, _, session := newSubscribedServer(t,
+		"omnifocus://task/t1", "omnifocus://task/t2",
+		"omnifocus://project/p-work", "omnifocus://project/p-home",
+		"omnifocus://projects")
+	inner := &mockClient{
+		projects: []omnifocus.Project{{ID: "p-work", Name: "Work"}, {ID: "p-home", Name: "Home"}},
+		tasks: []omnifocus.Task{
+			{ID: "t1", ContainingProjectID: strPtr("p-work")},
+			{ID: "t2", ContainingProjectID: strPtr("p-home")},
+		},
+	}
+	subs.restrict(omnifocus.NewScopedClient(inner, omnifocus.Scope{DenyProjects: []string{"Home"}}))
+
+	subs.publish(omnifocus.Change{Tasks: true, Projects: true})
+	subs.filtering.Wait()
+	got := session.received()
+	want := map[string]bool{
+		"notifications/resources/updated omnifocus://task/t1":        true,
+		"notifications/resources/updated omnifocus://project/p-work": true,
+		"notifications/resources/updated omnifocus://projects":       true,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected only in-scope resources to be updated, got %v", got)
+	}
+}
+
+func TestSubscriptions_Unsubscribe(t *testing.T) {
+	subs, s, session := newSubscribedServer(t, "omnifocus://projects")
+
+	sendResourceRequest(t, s, session, "resources/unsubscribe", "omnifocus://projects")
+	subs.publish(omnifocus.Change{Projects: true})
+	if got := session.received(); len(got) != 0 {
+		t.Errorf("expected no notifications after unsubscribing, got %v", got)
+	}
+
+	sendResourceRequest(t, s, session, "resources/subscribe", "omnifocus://projects")
+	s.UnregisterSession(context.Background(), session.SessionID())
+	if len(subs.sessions) != 0 {
+		t.Errorf("expected a closed session's subscriptions to be dropped, got %v", subs.sessions)
+	}
+}
+
+func TestAffects(t *testing.T) {
+	tests := []struct {
+		change omnifocus.Change
+		uri    string
+		want   bool
+	}{
+		{omnifocus.Change{ProjectIDs: []string{"p1"}}, "omnifocus://projects", true},
+		{omnifocus.Change{TaskIDs: []string{"t1"}}, "omnifocus://projects", false},
+		{omnifocus.Change{ProjectIDs: []string{"p1"}}, "omnifocus://project/p1", true},
+		{omnifocus.Change{ProjectIDs: []string{"p1"}}, "omnifocus://project/p2", false},
+		{omnifocus.Change{Tasks: true}, "omnifocus://project/p2", true},
+		{omnifocus.Change{TaskIDs: []string{"t1"}}, "omnifocus://task/t1", true},
+		{omnifocus.Change{TaskIDs: []string{"t1"}}, "omnifocus://task/t2", false},
+		{omnifocus.Change{Projects: true}, "omnifocus://task/t1", false},
+		{omnifocus.Change{Tags: true}, "omnifocus://tags", true},
+		{omnifocus.Change{Tasks: true}, "omnifocus://tags", false},
+		{omnifocus.Change{Tasks: true, Projects: true, Tags: true}, "omnifocus://unknown", false},
+	}
+	for _, tt := range tests {
+		if got := affects(tt.change, tt.uri); got != tt.want {
+			t.Errorf("affects(%+v, %q) = %v, want %v", tt.change, tt.uri, got, tt.want)
+		}
+	}
+}
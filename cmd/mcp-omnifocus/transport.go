@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transports accepted by --transport
+const (
+	transportStdio = "stdio"
+	transportSSE   = "sse"
+	transportHTTP  = "http"
+)
+
+var transports = []string{transportStdio, transportSSE, transportHTTP}
+
+const (
+	// defaultListenAddr only accepts connections from the same Mac
+	defaultListenAddr = "127.0.0.1:8080"
+	// httpEndpoint is where the http transport is served; the sse
+	// transport uses /sse and /message
+	httpEndpoint = "/mcp"
+	// shutdownTimeout bounds how long open requests may take to finish
+	// once a shutdown signal arrives
+	shutdownTimeout = 10 * time.Second
+)
+
+// httpTransport is an MCP transport served over HTTP
+type httpTransport interface {
+	http.Handler
+	// Shutdown ends open sessions, including long-lived event streams,
+	// and stops the HTTP server passed to newHTTPTransport
+	Shutdown(ctx context.Context) error
+}
+
+// newHTTPTransport returns the sse or http transport for s and points
+// srv's handler at it
+func newHTTPTransport(s *server.MCPServer, transport string, srv *http.Server) httpTransport {
+	if transport == transportSSE {
+		t := server.NewSSEServer(s, server.WithHTTPServer(srv))
+		srv.Handler = t
+		return t
+	}
+
+	// Sessions are kept so that resource subscriptions last between requests
+	t := server.NewStreamableHTTPServer(s,
+		server.WithStateful(true),
+		server.WithStreamableHTTPServer(srv),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(httpEndpoint, t)
+	srv.Handler = mux
+	return t
+}
+
+// serve runs s on the configured transport until it fails or the process
+// receives SIGINT or SIGTERM
+func serve(s *server.MCPServer, cfg Config) error {
+	if cfg.Transport == transportStdio {
+		// ServeStdio stops on the same signals
+		return server.ServeStdio(s)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		return err
+	}
+	tlsCfg, err := tlsConfig(cfg)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if tlsCfg != nil {
+		ln = tls.NewListener(ln, tlsCfg)
+		scheme = "https"
+	}
+	log.Printf("Serving MCP over %s on %s://%s", cfg.Transport, scheme, ln.Addr())
+	return serveHTTP(ctx, s, cfg.Transport, ln, auth)
+}
+
+// serveHTTP serves the sse or http transport on ln to callers that auth
+// accepts until ctx is done, then closes the open sessions and waits for
+// requests in progress to finish
+func serveHTTP(ctx context.Context, s *server.MCPServer, transport string, ln net.Listener, auth *authenticator) error {
+	srv := &http.Server{ReadHeaderTimeout: 10 * time.Second}
+	t := newHTTPTransport(s, transport, srv)
+	srv.Handler = auth.middleware(srv.Handler)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := t.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
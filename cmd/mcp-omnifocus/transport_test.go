@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const initializeRequest = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+
+// newToolServer returns an MCP server with the tools registered against an
+// empty mock client
+func newToolServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "0")
+	registerTools(s, &mockClient{})
+	return s
+}
+
+// postJSON posts a JSON-RPC message with the bearer token and session, if
+// set, returning the response
+func postJSON(t *testing.T, url, token, sessionID, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// readEvent reads the next server-sent event's type and data
+func readEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
+
+func TestHTTPTransport_StreamableHTTP(t *testing.T) {
+	srv := &http.Server{}
+	newHTTPTransport(newToolServer(), transportHTTP, srv)
+	ts := httptest.NewServer(srv.Handler)
+	defer ts.Close()
+
+	resp := postJSON(t, ts.URL+httpEndpoint, "", "", initializeRequest)
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("expected a session from initialize, got status %d and session %q", resp.StatusCode, sessionID)
+	}
+
+	resp = postJSON(t, ts.URL+httpEndpoint, "", sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "list_projects") {
+		t.Errorf("expected the tools to be listed, got %d: %s", resp.StatusCode, body)
+	}
+
+	if resp, err := http.Get(ts.URL + "/elsewhere"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected other paths to be not found, got %v, %v", resp, err)
+	}
+}
+
+func TestHTTPTransport_SSE(t *testing.T) {
+	srv := &http.Server{}
+	newHTTPTransport(newToolServer(), transportSSE, srv)
+	ts := httptest.NewServer(srv.Handler)
+	defer ts.Close()
+
+	stream, err := http.Get(ts.URL + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+
+	event, endpoint := readEvent(t, events)
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/message?sessionId=") {
+		t.Fatalf("expected the message endpoint, got %s %q", event, endpoint)
+	}
+
+	if resp := postJSON(t, ts.URL+endpoint, "", "", initializeRequest); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the message to be accepted, got %d", resp.StatusCode)
+	}
+	event, data := readEvent(t, events)
+	if event != "message" || !strings.Contains(data, `"serverInfo"`) {
+		t.Errorf("expected the initialize result on the stream, got %s %q", event, data)
+	}
+}
+
+func TestServeHTTP_GracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	auth := &authenticator{tokens: newTestTokenStore(t, testTokens)}
+	go func() { done <- serveHTTP(ctx, newToolServer(), transportSSE, ln, auth) }()
+
+	// An open event stream must not hold up the shutdown
+	req, _ := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/sse", nil)
+	req.Header.Set("Authorization", "Bearer "+readWriteToken)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	readEvent(t, bufio.NewReader(stream.Body))
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish")
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Error("expected the listener to be closed")
+	}
+}
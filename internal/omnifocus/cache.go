@@ -1,130 +1,642 @@
 package omnifocus
 
 import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strings"
 	"sync"
 	"time"
 )
 
 // cacheEntry holds cached data with expiration time
-type cacheEntry struct {
-	data      interface{}
+type cacheEntry[V any] struct {
+	key      string
+	data     V
+	storedAt time.Time
+	// expiresAt is the soft expiry, after which the entry is stale
 	expiresAt time.Time
+	// size is the approximate size of data in bytes, if tracked
+	size int
 }
 
-// Cache provides a simple in-memory cache with TTL
-type Cache struct {
-	mu      sync.RWMutex
-	entries map[string]*cacheEntry
-	ttl     time.Duration
-	enabled bool
+// Cache provides a simple in-memory cache of values of type V with TTL.
+// Entries are fresh for the TTL and may then be served stale for a further
+// stale TTL while they are refreshed. The cache can be bounded by entry
+// count and approximate size, evicting the least recently used entries.
+type Cache[V any] struct {
+	mu sync.Mutex
+	// entries maps keys to elements of lru, which holds *cacheEntry[V]
+	// values ordered from most to least recently used
+	entries   map[string]*list.Element
+	lru       *list.List
+	ttl       time.Duration
+	prefixTTL map[string]time.Duration
+	staleTTL  time.Duration
+	enabled   bool
+
+	maxEntries int
+	maxBytes   int
+	bytes      int
+
+	// Statistics, reported by Stats
+	hits          uint64
+	misses        uint64
+	staleHits     uint64
+	evictions     uint64
+	fills         uint64
+	fillTime      time.Duration
+	patches       uint64
+	invalidations map[string]uint64
+
+	// clone deep-copies values going in and out of the cache, so callers
+	// cannot modify cached data through a returned value
+	clone func(V) V
+	// disk optionally persists entries across restarts. It is only used
+	// without holding mu, so a slow disk never blocks reads from memory.
+	// Changes to it are queued in diskQueue under mu and run in that order
+	// by whoever next holds diskMu, so the disk changes in the same order
+	// as memory.
+	disk      *DiskCache
+	diskMu    sync.Mutex
+	diskQueue []func(*DiskCache)
+	// generation counts invalidations, so an entry read from disk is not
+	// kept if it was invalidated while being read
+	generation uint64
+	// flights coalesces concurrent fetches of the same key
+	flights flightGroup[V]
 }
 
 // NewCache creates a new cache with the specified TTL
-func NewCache(ttl time.Duration) *Cache {
-	return &Cache{
-		entries: make(map[string]*cacheEntry),
+func NewCache[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
 		ttl:     ttl,
 		enabled: ttl > 0, // Disable cache if TTL is 0 or negative
 	}
 }
 
-// Get retrieves a value from the cache if it exists and hasn't expired
-func (c *Cache) Get(key string) (interface{}, bool) {
+// SetClone sets the function used to deep-copy values. Without one, values
+// are shared with callers, which is only safe for immutable types.
+func (c *Cache[V]) SetClone(clone func(V) V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clone = clone
+}
+
+// SetLimits bounds the cache to maxEntries entries and roughly maxBytes
+// bytes of data, measured as JSON. Zero means no limit. When a limit is
+// exceeded the least recently used entries are evicted.
+func (c *Cache[V]) SetLimits(maxEntries, maxBytes int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxEntries = max(maxEntries, 0)
+	c.maxBytes = max(maxBytes, 0)
+	c.evict()
+}
+
+// SetPrefixTTL overrides the TTL for keys starting with prefix. When
+// several prefixes match, the longest wins.
+func (c *Cache[V]) SetPrefixTTL(prefix string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.prefixTTL == nil {
+		c.prefixTTL = make(map[string]time.Duration)
+	}
+	c.prefixTTL[prefix] = ttl
+}
+
+// ttlFor returns the TTL for key. The caller must hold c.mu.
+func (c *Cache[V]) ttlFor(key string) time.Duration {
+	ttl, matched := c.ttl, -1
+	for prefix, d := range c.prefixTTL {
+		if strings.HasPrefix(key, prefix) && len(prefix) > matched {
+			ttl, matched = d, len(prefix)
+		}
+	}
+	return ttl
+}
+
+// CacheStats is a snapshot of a cache's contents and counters
+type CacheStats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int    `json:"bytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	StaleHits uint64 `json:"staleHits"`
+	Evictions uint64 `json:"evictions"`
+	// Fills counts successful loads on a miss or refresh and AvgFillMs
+	// their mean duration
+	Fills     uint64  `json:"fills"`
+	AvgFillMs float64 `json:"avgFillMs"`
+	// Patches counts entries updated in place after a change
+	Patches uint64 `json:"patches"`
+	// Invalidations counts invalidations by key prefix; "*" is a full clear
+	Invalidations map[string]uint64 `json:"invalidations"`
+}
+
+// Stats returns a snapshot of the cache's statistics
+func (c *Cache[V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Entries:       c.lru.Len(),
+		Bytes:         c.bytes,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		StaleHits:     c.staleHits,
+		Evictions:     c.evictions,
+		Fills:         c.fills,
+		Patches:       c.patches,
+		Invalidations: make(map[string]uint64, len(c.invalidations)),
+	}
+	if c.fills > 0 {
+		stats.AvgFillMs = float64(c.fillTime.Microseconds()) / 1000 / float64(c.fills)
+	}
+	for prefix, n := range c.invalidations {
+		stats.Invalidations[prefix] = n
+	}
+	return stats
+}
+
+// countInvalidation records an invalidation of prefix. The caller must hold
+// c.mu.
+func (c *Cache[V]) countInvalidation(prefix string) {
+	if c.invalidations == nil {
+		c.invalidations = make(map[string]uint64)
+	}
+	c.invalidations[prefix]++
+}
+
+// Evictions returns the number of entries evicted to stay within the limits
+func (c *Cache[V]) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+// Len returns the number of entries held in memory, including expired ones
+// not yet cleaned up
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Bytes returns the approximate size of the entries held in memory. It is
+// only tracked while a byte limit is set.
+func (c *Cache[V]) Bytes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// SetStaleTTL sets how long after its TTL an entry may still be served as
+// stale. Zero, the default, drops entries as soon as they expire.
+func (c *Cache[V]) SetStaleTTL(staleTTL time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.staleTTL = max(staleTTL, 0)
+}
+
+// SetDisk makes the cache write entries through to disk, as JSON, and fall
+// back to disk on a miss. The disk cache should not be shared with another
+// Cache. It is best-effort: failures to read or write it are ignored.
+func (c *Cache[V]) SetDisk(disk *DiskCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.disk = disk
+}
+
+// copyOf returns a deep copy of v if the cache has a clone function
+func (c *Cache[V]) copyOf(v V) V {
+	if c.clone == nil {
+		return v
+	}
+	return c.clone(v)
+}
+
+// Get retrieves a value from the cache if it exists and hasn't expired. An
+// entry past its TTL counts as a miss, as Get does not return it.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	if !c.enabled {
+		var zero V
+		return zero, false
+	}
+	value, _, stale, found := c.lookup(key)
+	if stale {
+		var zero V
+		value, found = zero, false
+	}
+	c.count(found, false)
+	return value, found
+}
+
+// Lookup retrieves a value that has not passed its stale TTL, along with
+// its age and whether it has passed its TTL
+func (c *Cache[V]) Lookup(key string) (value V, age time.Duration, stale bool, found bool) {
 	if !c.enabled {
-		return nil, false
+		return value, 0, false, false
 	}
+	value, age, stale, found = c.lookup(key)
+	c.count(found, stale)
+	return value, age, stale, found
+}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// count records the outcome of a read in the statistics
+func (c *Cache[V]) count(found, stale bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	entry, exists := c.entries[key]
-	if !exists {
-		return nil, false
+	switch {
+	case !found:
+		c.misses++
+	case stale:
+		c.staleHits++
+	default:
+		c.hits++
 	}
+}
 
-	// Check if entry has expired
-	if time.Now().After(entry.expiresAt) {
-		return nil, false
+// lookup implements Lookup without counting the read
+func (c *Cache[V]) lookup(key string) (value V, age time.Duration, stale bool, found bool) {
+	c.mu.Lock()
+	var entry *cacheEntry[V]
+	if elem, exists := c.entries[key]; exists {
+		c.lru.MoveToFront(elem)
+		entry = elem.Value.(*cacheEntry[V])
 	}
+	staleTTL := c.staleTTL
+	c.mu.Unlock()
 
-	return entry.data, true
+	if entry == nil {
+		if entry = c.loadFromDisk(key); entry == nil {
+			return value, 0, false, false
+		}
+	}
+
+	now := time.Now()
+	if now.After(entry.expiresAt.Add(staleTTL)) {
+		return value, 0, false, false
+	}
+
+	return c.copyOf(entry.data), now.Sub(entry.storedAt), now.After(entry.expiresAt), true
 }
 
-// Set stores a value in the cache with the configured TTL
-func (c *Cache) Set(key string, value interface{}) {
+// Set stores a value in the cache with the configured TTL. The value is
+// encoded and written to disk without holding the cache's lock.
+func (c *Cache[V]) Set(key string, value V) {
 	if !c.enabled {
 		return
 	}
 
+	var data []byte
+	if c.needsEncoding() {
+		data, _ = json.Marshal(value)
+	}
+
+	c.mu.Lock()
+	ttl := c.ttlFor(key)
+	if ttl <= 0 {
+		c.remove(key)
+		c.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	entry := &cacheEntry[V]{
+		key:       key,
+		data:      c.copyOf(value),
+		storedAt:  now,
+		expiresAt: now.Add(ttl),
+	}
+	if c.maxBytes > 0 {
+		entry.size = len(data)
+	}
+	c.insert(entry)
+
+	c.unlockThenDisk(func(disk *DiskCache) {
+		if data != nil {
+			disk.Store(key, data, entry.storedAt, entry.expiresAt)
+		}
+	})
+}
+
+// unlockThenDisk queues fn to run on the disk cache, if there is one,
+// releases c.mu, which the caller must hold, and returns once fn has run.
+// Disk changes are made in the order their callers held c.mu.
+func (c *Cache[V]) unlockThenDisk(fn func(disk *DiskCache)) {
+	disk := c.disk
+	if disk == nil {
+		c.mu.Unlock()
+		return
+	}
+	c.diskQueue = append(c.diskQueue, fn)
+	c.mu.Unlock()
+
+	c.diskMu.Lock()
+	defer c.diskMu.Unlock()
+	c.runDiskQueue(disk)
+}
+
+// runDiskQueue runs the queued disk changes. The caller must hold c.diskMu.
+func (c *Cache[V]) runDiskQueue(disk *DiskCache) {
+	c.mu.Lock()
+	queue := c.diskQueue
+	c.diskQueue = nil
+	c.mu.Unlock()
+
+	for _, fn := range queue {
+		fn(disk)
+	}
+}
+
+// needsEncoding reports whether stored values must be encoded as JSON, to
+// write them to disk or to measure their size
+func (c *Cache[V]) needsEncoding() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.entries[key] = &cacheEntry{
-		data:      value,
-		expiresAt: time.Now().Add(c.ttl),
+	return c.disk != nil || c.maxBytes > 0
+}
+
+// insert adds or replaces an entry as the most recently used and evicts
+// entries beyond the limits. The caller must hold c.mu.
+func (c *Cache[V]) insert(entry *cacheEntry[V]) {
+	c.remove(entry.key)
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+	c.evict()
+}
+
+// remove deletes key from memory. The caller must hold c.mu.
+func (c *Cache[V]) remove(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.bytes -= elem.Value.(*cacheEntry[V]).size
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// evict removes least recently used entries until the cache is within its
+// limits. An entry larger than the byte limit on its own is not kept. The
+// caller must hold c.mu.
+func (c *Cache[V]) evict() {
+	for c.lru.Len() > 0 && ((c.maxEntries > 0 && c.lru.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.remove(c.lru.Back().Value.(*cacheEntry[V]).key)
+		c.evictions++
 	}
 }
 
+// Fetch runs fn to load key and caches the result. Concurrent fetches of
+// the same key share one run of fn, and each caller gets its own copy of
+// the result. If ctx is done first, Fetch returns ctx's error.
+func (c *Cache[V]) Fetch(ctx context.Context, key string, fn func(context.Context) (V, error)) (V, error) {
+	value, err := c.flights.Do(ctx, key, func(ctx context.Context) (V, error) {
+		start := time.Now()
+		v, err := fn(ctx)
+		if err == nil {
+			c.mu.Lock()
+			c.fills++
+			c.fillTime += time.Since(start)
+			c.mu.Unlock()
+		}
+		return v, err
+	}, func(v V) {
+		c.Set(key, v)
+	})
+	if err != nil {
+		return value, err
+	}
+	return c.copyOf(value), nil
+}
+
+// loadFromDisk reads key from the disk cache into memory, returning nil if
+// it is not on disk or cannot be decoded
+func (c *Cache[V]) loadFromDisk(key string) *cacheEntry[V] {
+	c.mu.Lock()
+	disk := c.disk
+	generation := c.generation
+	c.mu.Unlock()
+	if disk == nil {
+		return nil
+	}
+
+	// Wait for earlier disk changes, such as an invalidation of key
+	c.diskMu.Lock()
+	c.runDiskQueue(disk)
+	data, storedAt, expiresAt, found := disk.Load(key)
+	var value V
+	if found && json.Unmarshal(data, &value) != nil {
+		disk.Remove(key)
+		found = false
+	}
+	c.diskMu.Unlock()
+	if !found {
+		return nil
+	}
+
+	entry := &cacheEntry[V]{key: key, data: value, storedAt: storedAt, expiresAt: expiresAt}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		// Invalidated while it was read
+		return nil
+	}
+	if existing, ok := c.entries[key]; ok {
+		// Another caller stored a value meanwhile
+		return existing.Value.(*cacheEntry[V])
+	}
+	if c.maxBytes > 0 {
+		entry.size = len(data)
+	}
+	c.insert(entry)
+	return entry
+}
+
 // Invalidate removes a specific key from the cache
-func (c *Cache) Invalidate(key string) {
+func (c *Cache[V]) Invalidate(key string) {
 	if !c.enabled {
 		return
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	delete(c.entries, key)
+	c.countInvalidation(key)
+	c.remove(key)
+	c.generation++
+	c.unlockThenDisk(func(disk *DiskCache) { disk.Remove(key) })
 }
 
 // InvalidateAll clears all entries from the cache
-func (c *Cache) InvalidateAll() {
+func (c *Cache[V]) InvalidateAll() {
+	c.flights.ForgetPrefix("")
+
 	if !c.enabled {
 		return
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.entries = make(map[string]*cacheEntry)
+	c.countInvalidation("*")
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+	c.generation++
+	c.unlockThenDisk(func(disk *DiskCache) { disk.RemovePrefix("") })
 }
 
-// InvalidatePattern removes all keys matching a pattern (simple prefix match)
-func (c *Cache) InvalidatePattern(prefix string) {
+// InvalidatePattern removes all keys matching a pattern (simple prefix
+// match). Fetches of those keys already running are not joined or cached
+// afterwards, since they may have started before the change that caused
+// the invalidation.
+func (c *Cache[V]) InvalidatePattern(prefix string) {
+	c.flights.ForgetPrefix(prefix)
+
 	if !c.enabled {
 		return
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	c.countInvalidation(prefix)
 	for key := range c.entries {
 		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
-			delete(c.entries, key)
+			c.remove(key)
 		}
 	}
+	c.generation++
+	c.unlockThenDisk(func(disk *DiskCache) { disk.RemovePrefix(prefix) })
 }
 
-// Cleanup removes expired entries from the cache
-// This should be called periodically to prevent memory growth
-func (c *Cache) Cleanup() {
+// Patch updates every entry with a key starting with prefix in place. fn
+// gets a copy of the entry's value and returns the patched value, or false
+// if it cannot patch it, in which case the entry is removed. Patched entries
+// keep their expiry but count as stored now, as they reflect the latest
+// change. Like InvalidatePattern, fetches of those keys already running are
+// not cached afterwards, and entries only on disk are removed. Patched
+// values are encoded and written to disk without holding the cache's lock.
+func (c *Cache[V]) Patch(prefix string, fn func(key string, value V) (V, bool)) {
+	c.flights.ForgetPrefix(prefix)
+
 	if !c.enabled {
 		return
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	var patched []*cacheEntry[V]
+	removed := false
+	now := time.Now()
+	for key, elem := range c.entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		old := elem.Value.(*cacheEntry[V])
+		value, ok := fn(key, c.copyOf(old.data))
+		if !ok {
+			c.remove(key)
+			removed = true
+			continue
+		}
+		// The entry keeps its old size until the patched value is encoded
+		patched = append(patched, &cacheEntry[V]{
+			key:       key,
+			data:      value,
+			storedAt:  now,
+			expiresAt: old.expiresAt,
+			size:      old.size,
+		})
+	}
+	for _, entry := range patched {
+		c.insert(entry)
+		c.patches++
+	}
+	if removed {
+		c.countInvalidation(prefix)
+	}
+	c.generation++
+	encode := c.disk != nil || c.maxBytes > 0
+	c.unlockThenDisk(func(disk *DiskCache) { disk.RemovePrefix(prefix) })
+
+	if !encode || len(patched) == 0 {
+		return
+	}
+	// Cached values are never modified, so they can be read unlocked
+	encoded := make([][]byte, len(patched))
+	for i, entry := range patched {
+		encoded[i], _ = json.Marshal(entry.data)
+	}
+
+	c.mu.Lock()
+	var stored []int
+	for i, entry := range patched {
+		if elem, ok := c.entries[entry.key]; !ok || elem.Value != entry || encoded[i] == nil {
+			// Replaced or removed since it was patched
+			continue
+		}
+		if c.maxBytes > 0 {
+			c.bytes += len(encoded[i]) - entry.size
+			entry.size = len(encoded[i])
+		}
+		stored = append(stored, i)
+	}
+	c.evict()
+
+	c.unlockThenDisk(func(disk *DiskCache) {
+		for _, i := range stored {
+			disk.Store(patched[i].key, encoded[i], patched[i].storedAt, patched[i].expiresAt)
+		}
+	})
+}
+
+// InvalidateStoredBefore removes entries with keys starting with prefix
+// that were stored before t, i.e. that may predate a change made at t
+func (c *Cache[V]) InvalidateStoredBefore(prefix string, t time.Time) {
+	c.flights.ForgetPrefix(prefix)
 
+	if !c.enabled {
+		return
+	}
+
+	c.mu.Lock()
+	removed := false
+	for key, elem := range c.entries {
+		if strings.HasPrefix(key, prefix) && elem.Value.(*cacheEntry[V]).storedAt.Before(t) {
+			c.remove(key)
+			removed = true
+		}
+	}
+	if removed {
+		c.countInvalidation(prefix)
+	}
+	c.generation++
+	c.unlockThenDisk(func(disk *DiskCache) { disk.RemoveStoredBefore(prefix, t) })
+}
+
+// Cleanup removes entries past their stale TTL from the cache
+// This should be called periodically to prevent memory growth
+func (c *Cache[V]) Cleanup() {
+	if !c.enabled {
+		return
+	}
+
+	c.mu.Lock()
 	now := time.Now()
-	for key, entry := range c.entries {
-		if now.After(entry.expiresAt) {
-			delete(c.entries, key)
+	cutoff := now.Add(-c.staleTTL)
+	for key, elem := range c.entries {
+		if now.After(elem.Value.(*cacheEntry[V]).expiresAt.Add(c.staleTTL)) {
+			c.remove(key)
 		}
 	}
+	c.unlockThenDisk(func(disk *DiskCache) { disk.RemoveExpired(cutoff) })
 }
 
 // StartCleanupTimer starts a background goroutine that periodically cleans up expired entries
-func (c *Cache) StartCleanupTimer(interval time.Duration) {
+func (c *Cache[V]) StartCleanupTimer(interval time.Duration) {
 	if !c.enabled {
 		return
 	}
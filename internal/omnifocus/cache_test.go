@@ -1,12 +1,16 @@
 package omnifocus
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 func TestCacheGetSet(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	// Test setting and getting a value
 	cache.Set("test-key", "test-value")
@@ -15,13 +19,13 @@ func TestCacheGetSet(t *testing.T) {
 	if !found {
 		t.Error("Expected to find cached value")
 	}
-	if value.(string) != "test-value" {
+	if value != "test-value" {
 		t.Errorf("Expected 'test-value', got '%s'", value)
 	}
 }
 
 func TestCacheExpiration(t *testing.T) {
-	cache := NewCache(100 * time.Millisecond)
+	cache := NewCache[string](100 * time.Millisecond)
 
 	cache.Set("test-key", "test-value")
 
@@ -42,7 +46,7 @@ func TestCacheExpiration(t *testing.T) {
 }
 
 func TestCacheInvalidate(t *testing.T) {
-	cache := NewCache(10 * time.Second)
+	cache := NewCache[string](10 * time.Second)
 
 	cache.Set("test-key", "test-value")
 	cache.Invalidate("test-key")
@@ -54,7 +58,7 @@ func TestCacheInvalidate(t *testing.T) {
 }
 
 func TestCacheInvalidateAll(t *testing.T) {
-	cache := NewCache(10 * time.Second)
+	cache := NewCache[string](10 * time.Second)
 
 	cache.Set("key1", "value1")
 	cache.Set("key2", "value2")
@@ -72,7 +76,7 @@ func TestCacheInvalidateAll(t *testing.T) {
 }
 
 func TestCacheInvalidatePattern(t *testing.T) {
-	cache := NewCache(10 * time.Second)
+	cache := NewCache[string](10 * time.Second)
 
 	cache.Set("tasks:all", "all tasks")
 	cache.Set("tasks:project:123", "project 123 tasks")
@@ -96,7 +100,7 @@ func TestCacheInvalidatePattern(t *testing.T) {
 }
 
 func TestCacheDisabled(t *testing.T) {
-	cache := NewCache(0)
+	cache := NewCache[string](0)
 
 	cache.Set("test-key", "test-value")
 
@@ -107,7 +111,7 @@ func TestCacheDisabled(t *testing.T) {
 }
 
 func TestCacheCleanup(t *testing.T) {
-	cache := NewCache(50 * time.Millisecond)
+	cache := NewCache[string](50 * time.Millisecond)
 
 	cache.Set("key1", "value1")
 	cache.Set("key2", "value2")
@@ -135,7 +139,7 @@ func TestCacheCleanup(t *testing.T) {
 }
 
 func TestCacheGetNonExistent(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	_, found := cache.Get("non-existent-key")
 	if found {
@@ -144,7 +148,7 @@ func TestCacheGetNonExistent(t *testing.T) {
 }
 
 func TestCacheSetOverwrite(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	cache.Set("test-key", "initial-value")
 	cache.Set("test-key", "updated-value")
@@ -153,57 +157,91 @@ func TestCacheSetOverwrite(t *testing.T) {
 	if !found {
 		t.Error("Expected to find cached value")
 	}
-	if value.(string) != "updated-value" {
+	if value != "updated-value" {
 		t.Errorf("Expected 'updated-value', got '%s'", value)
 	}
 }
 
-func TestCacheMultipleTypes(t *testing.T) {
-	cache := NewCache(1 * time.Second)
-
-	// Test different value types
-	cache.Set("string", "test")
-	cache.Set("int", 42)
-	cache.Set("bool", true)
-	cache.Set("slice", []string{"a", "b", "c"})
-	cache.Set("map", map[string]int{"x": 1, "y": 2})
+func TestCacheValueTypes(t *testing.T) {
+	ints := NewCache[int](1 * time.Second)
+	ints.Set("int", 42)
+	if num, found := ints.Get("int"); !found || num != 42 {
+		t.Error("Failed to retrieve int value")
+	}
 
-	// Verify all types can be retrieved
-	str, found := cache.Get("string")
-	if !found || str.(string) != "test" {
-		t.Error("Failed to retrieve string value")
+	slices := NewCache[[]string](1 * time.Second)
+	slices.Set("slice", []string{"a", "b", "c"})
+	if slice, found := slices.Get("slice"); !found || len(slice) != 3 {
+		t.Error("Failed to retrieve slice value")
 	}
 
-	num, found := cache.Get("int")
-	if !found || num.(int) != 42 {
-		t.Error("Failed to retrieve int value")
+	maps := NewCache[map[string]int](1 * time.Second)
+	maps.Set("map", map[string]int{"x": 1, "y": 2})
+	if m, found := maps.Get("map"); !found || m["x"] != 1 {
+		t.Error("Failed to retrieve map value")
 	}
+}
+
+func TestCacheCloneIsolatesCallers(t *testing.T) {
+	cache := NewCache[[]Task](1 * time.Second)
+	cache.SetClone(cloneAll[Task])
+
+	tasks := []Task{{ID: "t1", Name: "Original", Tags: []string{"home"}, DueDate: strPtr("2024-01-01")}}
+	cache.Set("tasks:all", tasks)
+
+	// Modifying the slice that was stored must not reach the cache
+	tasks[0].Name = "Changed by caller"
+	tasks[0].Tags[0] = "work"
 
-	b, found := cache.Get("bool")
-	if !found || b.(bool) != true {
-		t.Error("Failed to retrieve bool value")
+	got, _ := cache.Get("tasks:all")
+	if got[0].Name != "Original" || got[0].Tags[0] != "home" {
+		t.Fatalf("cache was modified through the stored slice: %+v", got[0])
 	}
 
-	slice, found := cache.Get("slice")
-	if !found || len(slice.([]string)) != 3 {
-		t.Error("Failed to retrieve slice value")
+	// Nor may modifying a returned value, including through pointers
+	got[0].Name = "Changed again"
+	*got[0].DueDate = "2099-12-31"
+	got = append(got[:0], Task{ID: "other"})
+
+	again, _ := cache.Get("tasks:all")
+	if again[0].ID != "t1" || again[0].Name != "Original" || *again[0].DueDate != "2024-01-01" {
+		t.Errorf("cache was modified through a returned slice: %+v", again[0])
 	}
+}
 
-	m, found := cache.Get("map")
-	if !found || m.(map[string]int)["x"] != 1 {
-		t.Error("Failed to retrieve map value")
+func TestCacheCloneConcurrentMutation(t *testing.T) {
+	cache := NewCache[[]Task](1 * time.Second)
+	cache.SetClone(cloneAll[Task])
+	cache.Set("tasks:all", []Task{{ID: "t1", Tags: []string{"a"}}})
+
+	// Run with -race: callers mutating their copies must not race
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tasks, _ := cache.Get("tasks:all")
+				tasks[0].Name = fmt.Sprint(n, j)
+				tasks[0].Tags = append(tasks[0].Tags, "b")
+				if j%10 == 0 {
+					cache.Set("tasks:all", tasks)
+				}
+			}
+		}(i)
 	}
+	wg.Wait()
 }
 
 func TestCacheInvalidateNonExistent(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	// Invalidating non-existent key should not panic
 	cache.Invalidate("non-existent")
 }
 
 func TestCacheInvalidatePatternNoMatch(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	cache.Set("tasks:all", "all tasks")
 	cache.Set("projects:all", "all projects")
@@ -221,7 +259,7 @@ func TestCacheInvalidatePatternNoMatch(t *testing.T) {
 }
 
 func TestCacheInvalidatePatternExactMatch(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	cache.Set("tasks", "tasks")
 	cache.Set("tasks:all", "all tasks")
@@ -239,35 +277,35 @@ func TestCacheInvalidatePatternExactMatch(t *testing.T) {
 }
 
 func TestCacheCleanupDisabled(t *testing.T) {
-	cache := NewCache(0)
+	cache := NewCache[string](0)
 
 	// Should not panic when cache is disabled
 	cache.Cleanup()
 }
 
 func TestCacheInvalidateAllDisabled(t *testing.T) {
-	cache := NewCache(0)
+	cache := NewCache[string](0)
 
 	// Should not panic when cache is disabled
 	cache.InvalidateAll()
 }
 
 func TestCacheInvalidateDisabled(t *testing.T) {
-	cache := NewCache(0)
+	cache := NewCache[string](0)
 
 	// Should not panic when cache is disabled
 	cache.Invalidate("test-key")
 }
 
 func TestCacheInvalidatePatternDisabled(t *testing.T) {
-	cache := NewCache(0)
+	cache := NewCache[string](0)
 
 	// Should not panic when cache is disabled
 	cache.InvalidatePattern("test:")
 }
 
 func TestCacheNegativeTTL(t *testing.T) {
-	cache := NewCache(-1 * time.Second)
+	cache := NewCache[string](-1 * time.Second)
 
 	cache.Set("test-key", "test-value")
 
@@ -278,21 +316,21 @@ func TestCacheNegativeTTL(t *testing.T) {
 }
 
 func TestCacheCleanupEmptyCache(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	// Should not panic on empty cache
 	cache.Cleanup()
 }
 
 func TestCacheInvalidateAllEmptyCache(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[string](1 * time.Second)
 
 	// Should not panic on empty cache
 	cache.InvalidateAll()
 }
 
 func TestCacheStartCleanupTimer(t *testing.T) {
-	cache := NewCache(50 * time.Millisecond)
+	cache := NewCache[string](50 * time.Millisecond)
 
 	cache.Set("key1", "value1")
 	cache.Set("key2", "value2")
@@ -320,14 +358,14 @@ func TestCacheStartCleanupTimer(t *testing.T) {
 }
 
 func TestCacheStartCleanupTimerDisabled(t *testing.T) {
-	cache := NewCache(0)
+	cache := NewCache[string](0)
 
 	// Should not panic when cache is disabled
 	cache.StartCleanupTimer(100 * time.Millisecond)
 }
 
 func TestCacheConcurrentAccess(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[int](1 * time.Second)
 	done := make(chan bool)
 
 	// Concurrent writes
@@ -363,7 +401,7 @@ func TestCacheConcurrentAccess(t *testing.T) {
 }
 
 func TestCacheConcurrentInvalidation(t *testing.T) {
-	cache := NewCache(1 * time.Second)
+	cache := NewCache[int](1 * time.Second)
 	done := make(chan bool)
 
 	// Set initial values
@@ -398,3 +436,166 @@ func TestCacheConcurrentInvalidation(t *testing.T) {
 
 	// Should not panic
 }
+
+func TestCacheStaleWindow(t *testing.T) {
+	cache := NewCache[string](50 * time.Millisecond)
+	cache.SetStaleTTL(150 * time.Millisecond)
+
+	cache.Set("test-key", "test-value")
+	time.Sleep(80 * time.Millisecond)
+
+	// Past the TTL: Get misses but Lookup serves the value as stale
+	if _, found := cache.Get("test-key"); found {
+		t.Error("Expected Get to miss a stale value")
+	}
+	value, age, stale, found := cache.Lookup("test-key")
+	if !found || !stale || value != "test-value" {
+		t.Errorf("Expected stale value, got value=%v stale=%v found=%v", value, stale, found)
+	}
+	if age < 80*time.Millisecond {
+		t.Errorf("Expected age of at least 80ms, got %s", age)
+	}
+
+	// Past the stale TTL the value is gone
+	time.Sleep(150 * time.Millisecond)
+	if _, _, _, found := cache.Lookup("test-key"); found {
+		t.Error("Expected value to be gone after the stale TTL")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewCache[string](time.Minute)
+	cache.SetLimits(2, 0)
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Get("a") // a is now more recent than b
+	cache.Set("c", "3")
+
+	if _, found := cache.Get("b"); found {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, found := cache.Get("a"); !found {
+		t.Error("Expected recently read entry to remain")
+	}
+	if cache.Len() != 2 || cache.Evictions() != 1 {
+		t.Errorf("Expected 2 entries and 1 eviction, got %d and %d", cache.Len(), cache.Evictions())
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	cache := NewCache[string](time.Minute)
+	// Each value below is 12 bytes as JSON
+	cache.SetLimits(0, 30)
+
+	cache.Set("a", "0123456789")
+	cache.Set("b", "0123456789")
+	if cache.Bytes() != 24 {
+		t.Fatalf("Expected 24 bytes tracked, got %d", cache.Bytes())
+	}
+	cache.Set("c", "0123456789")
+	if _, found := cache.Get("a"); found || cache.Bytes() != 24 {
+		t.Errorf("Expected oldest entry evicted to stay under 30 bytes, bytes=%d", cache.Bytes())
+	}
+
+	// A value over the limit on its own is not kept
+	cache.Set("big", strings.Repeat("x", 40))
+	if _, found := cache.Get("big"); found {
+		t.Error("Expected oversized value not to be cached")
+	}
+	if cache.Bytes() > 30 {
+		t.Errorf("Expected at most 30 bytes, got %d", cache.Bytes())
+	}
+
+	// Replacing and invalidating keep the accounting right
+	cache.Set("x", "1")
+	cache.InvalidateAll()
+	if cache.Bytes() != 0 || cache.Len() != 0 {
+		t.Errorf("Expected empty cache, got %d entries and %d bytes", cache.Len(), cache.Bytes())
+	}
+}
+
+func TestCachePrefixTTL(t *testing.T) {
+	cache := NewCache[string](50 * time.Millisecond)
+	cache.SetPrefixTTL("tags:", time.Minute)
+	cache.SetPrefixTTL("tasks:", time.Minute)
+	cache.SetPrefixTTL("tasks:project:", 0)
+
+	cache.Set("tags:all", "tags")
+	cache.Set("tasks:all", "tasks")
+	cache.Set("tasks:project:1", "project tasks")
+	cache.Set("projects:all", "projects")
+
+	if _, found := cache.Get("tasks:project:1"); found {
+		t.Error("Expected zero TTL prefix not to be cached")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, found := cache.Get("projects:all"); found {
+		t.Error("Expected default TTL to apply without an override")
+	}
+	if _, found := cache.Get("tags:all"); !found {
+		t.Error("Expected tags to use their longer TTL")
+	}
+	if _, found := cache.Get("tasks:all"); !found {
+		t.Error("Expected tasks:all to use the tasks: TTL, not tasks:project:")
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	cache := NewCache[string](50 * time.Millisecond)
+	cache.SetStaleTTL(time.Minute)
+	ctx := context.Background()
+
+	cache.Get("tasks:all") // miss
+	cache.Fetch(ctx, "tasks:all", func(context.Context) (string, error) {
+		time.Sleep(5 * time.Millisecond)
+		return "tasks", nil
+	})
+	cache.Get("tasks:all") // hit
+	time.Sleep(60 * time.Millisecond)
+	cache.Lookup("tasks:all") // stale
+	cache.Get("tasks:all")    // miss, as Get does not return stale entries
+	cache.InvalidatePattern("tasks:")
+	cache.InvalidatePattern("tasks:")
+	cache.InvalidateAll()
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.StaleHits != 1 {
+		t.Errorf("unexpected hit counts %+v", stats)
+	}
+	if stats.Fills != 1 || stats.AvgFillMs < 5 {
+		t.Errorf("expected one fill of at least 5ms, got %+v", stats)
+	}
+	if stats.Invalidations["tasks:"] != 2 || stats.Invalidations["*"] != 1 {
+		t.Errorf("unexpected invalidations %v", stats.Invalidations)
+	}
+}
+
+func TestCachePatch(t *testing.T) {
+	cache := NewCache[[]Task](time.Minute)
+	cache.SetClone(cloneAll[Task])
+	cache.Set("tasks:all", []Task{{ID: "t1", Name: "Old"}})
+	cache.Set("tasks:project:p1", []Task{{ID: "t2"}})
+	cache.Set("projects:all", []Task{{ID: "p1"}})
+
+	cache.Patch("tasks:", func(key string, tasks []Task) ([]Task, bool) {
+		if key != "tasks:all" {
+			return nil, false
+		}
+		tasks[0].Name = "New"
+		return tasks, true
+	})
+
+	if tasks, found := cache.Get("tasks:all"); !found || tasks[0].Name != "New" {
+		t.Errorf("Expected the patched list, got %v (found=%v)", tasks, found)
+	}
+	if _, found := cache.Get("tasks:project:p1"); found {
+		t.Error("Expected an entry that could not be patched to be removed")
+	}
+	if _, found := cache.Get("projects:all"); !found {
+		t.Error("Expected entries outside the prefix to be untouched")
+	}
+	if stats := cache.Stats(); stats.Patches != 1 || stats.Invalidations["tasks:"] != 1 {
+		t.Errorf("Expected 1 patch and 1 invalidation, got %+v", stats)
+	}
+}
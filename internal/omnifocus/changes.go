@@ -0,0 +1,185 @@
+package omnifocus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ProbeDatabase returns markers of the database's current state. It is much
+// cheaper than listing, and does not launch OmniFocus if it is closed.
+func (c *Client) ProbeDatabase(ctx context.Context) (*DatabaseState, error) {
+	output, err := c.executeJXA(ctx, "database_state.jxa")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScriptError("database_state", output); err != nil {
+		return nil, err
+	}
+
+	var state DatabaseState
+	if err := json.Unmarshal(output, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse database state: %w", err)
+	}
+	return &state, nil
+}
+
+// changeDetector invalidates cached lists when the database changes outside
+// the server, e.g. on another device or in the OmniFocus app
+type changeDetector struct {
+	client *Client
+	// last is the previous state seen while OmniFocus was running
+	last *DatabaseState
+}
+
+// StartChangeDetection probes the database every interval and invalidates
+// the cached lists affected by changes made outside the server. It returns
+// a function that stops probing.
+func (c *Client) StartChangeDetection(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &changeDetector{client: c}
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			d.check(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// check probes the database once and invalidates what has changed
+func (d *changeDetector) check(ctx context.Context) {
+	start := time.Now()
+	state, err := d.client.ProbeDatabase(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Change detection probe failed: %v", err)
+		}
+		return
+	}
+	if !state.Running {
+		return
+	}
+	d.apply(state, start)
+	d.last = state
+}
+
+// change describes how one kind of item changed between two probes
+type change struct {
+	// all means every list of that kind may be wrong, e.g. after a deletion
+	all bool
+	// since, if set, means lists fetched before it may be wrong
+	since time.Time
+}
+
+// diffMarker compares a marker with the previous one, which is nil on the
+// first probe, for a probe started at probedAt. A count change invalidates
+// everything, since deletions leave no modification date behind. Any other
+// change invalidates every list fetched before the probe: an edit synced
+// from another device can carry a modification date older than lists
+// fetched since, so the date cannot tell which lists predate it.
+func diffMarker(last *ChangeMarker, cur ChangeMarker, probedAt time.Time) change {
+	if last == nil {
+		// Lists loaded from the disk cache predate at least the latest
+		// modification
+		if cur.Modified == nil {
+			return change{}
+		}
+		t, err := time.Parse(time.RFC3339Nano, *cur.Modified)
+		if err != nil {
+			return change{all: true}
+		}
+		return change{since: t}
+	}
+	if cur.Count != last.Count {
+		return change{all: true}
+	}
+	if cur.Fingerprint != last.Fingerprint || !equalPtr(cur.Modified, last.Modified) {
+		return change{since: probedAt}
+	}
+	return change{}
+}
+
+// equalPtr reports whether a and b are both nil or point to equal values
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// applyChange invalidates the lists in cache affected by ch
+func applyChange[V any](cache *Cache[V], prefix string, ch change) {
+	switch {
+	case ch.all:
+		cache.InvalidatePattern(prefix)
+	case !ch.since.IsZero():
+		cache.InvalidateStoredBefore(prefix, ch.since)
+	}
+}
+
+// apply invalidates the caches affected by the differences between the
+// last state and state, probed at probedAt. On the first probe, lists
+// fetched before the latest modification are dropped, which covers lists
+// loaded from the disk cache.
+func (d *changeDetector) apply(state *DatabaseState, probedAt time.Time) {
+	var projects, tasks, tags change
+	if d.last == nil {
+		projects = diffMarker(nil, state.Projects, probedAt)
+		tasks = diffMarker(nil, state.Tasks, probedAt)
+		tags = diffMarker(nil, state.Tags, probedAt)
+	} else {
+		projects = diffMarker(&d.last.Projects, state.Projects, probedAt)
+		tasks = diffMarker(&d.last.Tasks, state.Tasks, probedAt)
+		tags = diffMarker(&d.last.Tags, state.Tags, probedAt)
+		if tasks != (change{}) && equalPtr(state.Tasks.Modified, d.last.Tasks.Modified) {
+			// The edit is older than the latest modification, so the
+			// replica's next incremental read would not fetch it
+			d.client.resetReplica()
+		}
+	}
+
+	c := d.client
+	applyChange(c.projectCache, "projects:", projects)
+	// Projects carry task counts, so task changes affect them too
+	applyChange(c.projectCache, "projects:", tasks)
+	applyChange(c.taskCache, "tasks:", tasks)
+	applyChange(c.tagCache, "tags:", tags)
+	if tags != (change{}) {
+		// The replica's tasks carry tag names, and renaming a tag does not
+		// mark its tasks as modified
+		c.resetReplica()
+	}
+
+	if projects == (change{}) && tasks == (change{}) && tags == (change{}) {
+		return
+	}
+	if d.last != nil {
+		log.Printf("Detected changes made outside the server; refreshing affected lists")
+	}
+	c.notifyChange(Change{
+		// Projects carry task counts, so task changes affect them too
+		Tasks:       tasks != (change{}),
+		Projects:    projects != (change{}) || tasks != (change{}),
+		Tags:        tags != (change{}),
+		ListChanged: projects.all || tasks.all || tags.all,
+	})
+}
@@ -0,0 +1,210 @@
+package omnifocus
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// probeFixture serves list scripts and a database state that tests can
+// change between probes.
+type probeFixture struct {
+	mu     sync.Mutex
+	state  DatabaseState
+	probes atomic.Int32
+}
+
+func (f *probeFixture) setState(edit func(*DatabaseState)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	edit(&f.state)
+}
+
+func (f *probeFixture) run(script string, _ ...string) ([]byte, error) {
+	switch script {
+	case "database_state.jxa":
+		f.probes.Add(1)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return mustJSON(f.state), nil
+	case "list_projects.jxa":
+		return mustJSON([]Project{{ID: "p1"}}), nil
+	case "list_tags.jxa":
+		return mustJSON([]Tag{{ID: "tag1"}}), nil
+	}
+	return mustJSON([]Task{{ID: "t1"}}), nil
+}
+
+func newProbeFixture(t *testing.T) (*probeFixture, *Client) {
+	t.Helper()
+	f := &probeFixture{state: DatabaseState{
+		Running:  true,
+		Projects: ChangeMarker{Count: 1, Modified: strPtr("2024-01-01T00:00:00.000Z")},
+		Tasks:    ChangeMarker{Count: 1, Modified: strPtr("2024-01-01T00:00:00.000Z")},
+		Tags:     ChangeMarker{Count: 1, Fingerprint: "aaaa"},
+	}}
+	return f, newTestClient(f.run)
+}
+
+// fillCaches lists everything so each cache holds one entry
+func fillCaches(c *Client) {
+	ctx := context.Background()
+	c.ListProjects(ctx)
+	c.ListTasks(ctx, "")
+	c.ListTags(ctx)
+}
+
+func cached(c *Client) (projects, tasks, tags bool) {
+	_, projects = c.projectCache.Get("projects:all")
+	_, tasks = c.taskCache.Get("tasks:all")
+	_, tags = c.tagCache.Get("tags:all")
+	return
+}
+
+func TestChangeDetection_FirstProbeDropsOlderLists(t *testing.T) {
+	f, c := newProbeFixture(t)
+	fillCaches(c)
+
+	// Tasks were modified after the lists were fetched, e.g. while the
+	// server was stopped and the lists came from the disk cache
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	f.setState(func(s *DatabaseState) { s.Tasks.Modified = &future })
+
+	d := &changeDetector{client: c}
+	d.check(context.Background())
+
+	projects, tasks, tags := cached(c)
+	if projects || tasks {
+		t.Error("expected tasks and projects fetched before the change to be dropped")
+	}
+	if !tags {
+		t.Error("expected tags to stay cached")
+	}
+}
+
+func TestChangeDetection_CountAndFingerprintChanges(t *testing.T) {
+	f, c := newProbeFixture(t)
+	d := &changeDetector{client: c}
+	d.check(context.Background())
+	fillCaches(c)
+
+	// Nothing changed
+	d.check(context.Background())
+	if projects, tasks, tags := cached(c); !projects || !tasks || !tags {
+		t.Fatal("expected an unchanged probe to keep every list")
+	}
+
+	// A tag was renamed
+	f.setState(func(s *DatabaseState) { s.Tags.Fingerprint = "bbbb" })
+	d.check(context.Background())
+	if projects, tasks, tags := cached(c); !projects || !tasks || tags {
+		t.Errorf("expected only tags dropped, got projects=%v tasks=%v tags=%v", projects, tasks, tags)
+	}
+
+	// A task was deleted, which changes no modification date
+	fillCaches(c)
+	f.setState(func(s *DatabaseState) { s.Tasks.Count = 0 })
+	d.check(context.Background())
+	if projects, tasks, tags := cached(c); projects || tasks || !tags {
+		t.Errorf("expected tasks and projects dropped, got projects=%v tasks=%v tags=%v", projects, tasks, tags)
+	}
+}
+
+func TestChangeDetection_OlderSyncedEdit(t *testing.T) {
+	f, c := newProbeFixture(t)
+	d := &changeDetector{client: c}
+	d.check(context.Background())
+	// The lists are fetched long after the latest modification
+	fillCaches(c)
+
+	// An edit synced from another device keeps its older modification
+	// date, so only the fingerprint of the dates changes
+	f.setState(func(s *DatabaseState) { s.Tasks.Fingerprint = "cccc" })
+	d.check(context.Background())
+	if projects, tasks, tags := cached(c); projects || tasks || !tags {
+		t.Errorf("expected tasks and projects dropped, got projects=%v tasks=%v tags=%v", projects, tasks, tags)
+	}
+
+	// Lists fetched after the probe that saw the change are kept
+	fillCaches(c)
+	d.check(context.Background())
+	if projects, tasks, tags := cached(c); !projects || !tasks || !tags {
+		t.Error("expected lists fetched after the change to be kept")
+	}
+}
+
+func TestChangeDetection_DescribesChanges(t *testing.T) {
+	f, c := newProbeFixture(t)
+	d := &changeDetector{client: c}
+	var changes []Change
+	c.OnChange(func(ch Change) { changes = append(changes, ch) })
+	d.check(context.Background())
+	changes = nil
+
+	d.check(context.Background())
+	if len(changes) != 0 {
+		t.Fatalf("expected no change from an unchanged probe, got %+v", changes)
+	}
+
+	// A task was edited
+	f.setState(func(s *DatabaseState) { s.Tasks.Modified = strPtr("2024-01-02T00:00:00.000Z") })
+	d.check(context.Background())
+	// A tag was added
+	f.setState(func(s *DatabaseState) { s.Tags.Count = 2 })
+	d.check(context.Background())
+
+	want := []Change{
+		{Tasks: true, Projects: true},
+		{Tags: true, ListChanged: true},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i := range want {
+		if changes[i].Tasks != want[i].Tasks || changes[i].Projects != want[i].Projects ||
+			changes[i].Tags != want[i].Tags || changes[i].ListChanged != want[i].ListChanged {
+			t.Errorf("change %d: expected %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestChangeDetection_IgnoresClosedApp(t *testing.T) {
+	f, c := newProbeFixture(t)
+	d := &changeDetector{client: c}
+	d.check(context.Background())
+	fillCaches(c)
+
+	f.setState(func(s *DatabaseState) { *s = DatabaseState{Running: false} })
+	d.check(context.Background())
+	if projects, tasks, tags := cached(c); !projects || !tasks || !tags {
+		t.Error("expected a closed app to leave the cache alone")
+	}
+	if d.last == nil || !d.last.Running {
+		t.Error("expected the last running state to be kept")
+	}
+}
+
+func TestStartChangeDetection(t *testing.T) {
+	f, c := newProbeFixture(t)
+	fillCaches(c)
+	stop := c.StartChangeDetection(5 * time.Millisecond)
+	defer stop()
+
+	// Change the database once the first probe has recorded its state
+	for f.probes.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	f.setState(func(s *DatabaseState) { s.Tasks.Count = 2 })
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, tasks, _ := cached(c); !tasks {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the background probe to drop the task list")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,6 +1,8 @@
 package omnifocus
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,26 +10,67 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 )
 
 // OmniFocusClient defines the interface for all OmniFocus operations.
+// Every method honours cancellation and deadlines on ctx.
 type OmniFocusClient interface {
-	ListProjects() ([]Project, error)
-	ListTasks(projectID string) ([]Task, error)
-	ListTags() ([]Tag, error)
-	CreateTask(req CreateTaskRequest) (*OperationResult, error)
-	CreateProject(req CreateProjectRequest) (*OperationResult, error)
-	UpdateTask(req UpdateTaskRequest) (*OperationResult, error)
-	CompleteTask(taskID string) (*OperationResult, error)
+	ListProjects(ctx context.Context) ([]Project, error)
+	ListTasks(ctx context.Context, projectID string) ([]Task, error)
+	ListTags(ctx context.Context) ([]Tag, error)
+	CreateTask(ctx context.Context, req CreateTaskRequest) (*OperationResult, error)
+	CreateProject(ctx context.Context, req CreateProjectRequest) (*OperationResult, error)
+	UpdateTask(ctx context.Context, req UpdateTaskRequest) (*OperationResult, error)
+	CompleteTask(ctx context.Context, taskID string) (*OperationResult, error)
 }
 
+// Operations lists the names of the operations backed by a JXA script. They
+// are the valid keys for SetOperationTimeout.
+var Operations = []string{
+	"list_projects",
+	"list_tasks",
+	"list_tags",
+	"database_state",
+	"create_task",
+	"create_project",
+	"update_task",
+	"complete_task",
+}
+
+// CacheKeyPrefixes are the prefixes of the cache keys for each kind of
+// list. Keys for a single project's tasks start with "tasks:project:".
+var CacheKeyPrefixes = []string{"projects:", "tasks:", "tags:"}
+
 // Client provides methods to interact with OmniFocus
 type Client struct {
 	scriptsDir string
-	cache      *Cache
+	// Each kind of list has its own cache, keyed by "projects:all",
+	// "tasks:all", "tasks:project:<id>" and "tags:all".
+	projectCache *Cache[[]Project]
+	taskCache    *Cache[[]Task]
+	tagCache     *Cache[[]Tag]
+	// replica, if set, keeps a local copy of every task so lists can be
+	// refreshed incrementally.
+	replica *taskReplica
+	// timeZone is passed to osascript as TZ so dates without an explicit
+	// offset are interpreted in that zone; empty uses the system zone.
+	timeZone string
+	// timeout bounds each osascript run; zero means no limit.
+	timeout time.Duration
+	// operationTimeouts overrides timeout for individual operations.
+	operationTimeouts map[string]time.Duration
+	// retry controls retries of transient failures.
+	retry RetryPolicy
+	// scheduler bounds concurrent script executions.
+	scheduler *Scheduler
+	// listeners are called after cached data changes; see OnChange.
+	listenersMu sync.Mutex
+	listeners   []func(Change)
 	// executor overrides the default osascript runner; used in tests.
-	executor func(scriptName string, args ...string) ([]byte, error)
+	executor func(ctx context.Context, scriptName string, args ...string) ([]byte, error)
 }
 
 // NewClient creates a new OmniFocus client with auto-detected scripts directory
@@ -45,17 +88,26 @@ func NewClientWithPath(scriptsPath string) *Client {
 // NewClientWithCache creates a new OmniFocus client with specified scripts directory and cache TTL
 // If cacheTTL is 0, caching is disabled
 func NewClientWithCache(scriptsPath string, cacheTTL time.Duration) *Client {
-	cache := NewCache(cacheTTL)
+	c := &Client{
+		scriptsDir:   scriptsPath,
+		projectCache: NewCache[[]Project](cacheTTL),
+		taskCache:    NewCache[[]Task](cacheTTL),
+		tagCache:     NewCache[[]Tag](cacheTTL),
+		retry:        DefaultRetryPolicy(),
+		scheduler:    NewScheduler(DefaultMaxConcurrentReads, DefaultMaxConcurrentWrites),
+	}
+	c.projectCache.SetClone(cloneAll[Project])
+	c.taskCache.SetClone(cloneAll[Task])
+	c.tagCache.SetClone(cloneAll[Tag])
 
 	// Start cleanup timer to remove expired entries every minute
 	if cacheTTL > 0 {
-		cache.StartCleanupTimer(1 * time.Minute)
+		c.projectCache.StartCleanupTimer(1 * time.Minute)
+		c.taskCache.StartCleanupTimer(1 * time.Minute)
+		c.tagCache.StartCleanupTimer(1 * time.Minute)
 	}
 
-	return &Client{
-		scriptsDir: scriptsPath,
-		cache:      cache,
-	}
+	return c
 }
 
 // GetScriptsDir returns the path to the scripts directory
@@ -64,6 +116,221 @@ func (c *Client) GetScriptsDir() string {
 	return c.scriptsDir
 }
 
+// SetTimeZone sets the IANA time zone used when OmniFocus interprets dates
+func (c *Client) SetTimeZone(tz string) {
+	c.timeZone = tz
+}
+
+// SetTimeout sets the maximum duration of a single script execution.
+// A zero duration disables the limit.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// SetOperationTimeout overrides the timeout for one operation, e.g.
+// "list_tasks" on a large database. A zero duration disables the limit.
+func (c *Client) SetOperationTimeout(operation string, timeout time.Duration) {
+	if c.operationTimeouts == nil {
+		c.operationTimeouts = make(map[string]time.Duration)
+	}
+	c.operationTimeouts[operation] = timeout
+}
+
+// SetRetryPolicy sets how transient failures of reads and idempotent writes
+// are retried
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// SetConcurrency sets how many reads and writes may run against OmniFocus at
+// once. It must be called before the client is used.
+func (c *Client) SetConcurrency(maxReads, maxWrites int) {
+	c.scheduler = NewScheduler(maxReads, maxWrites)
+}
+
+// SetStaleTTL sets how long after the cache TTL a list may still be served
+// while it is refreshed in the background
+func (c *Client) SetStaleTTL(staleTTL time.Duration) {
+	c.projectCache.SetStaleTTL(staleTTL)
+	c.taskCache.SetStaleTTL(staleTTL)
+	c.tagCache.SetStaleTTL(staleTTL)
+}
+
+// SetCacheLimits bounds each of the project, task and tag caches to
+// maxEntries entries and roughly maxBytes bytes, evicting the least recently
+// used lists beyond that. Zero means no limit.
+func (c *Client) SetCacheLimits(maxEntries, maxBytes int) {
+	c.projectCache.SetLimits(maxEntries, maxBytes)
+	c.taskCache.SetLimits(maxEntries, maxBytes)
+	c.tagCache.SetLimits(maxEntries, maxBytes)
+}
+
+// SetCacheTTL overrides the cache TTL for keys starting with prefix, e.g.
+// "tags:" or "tasks:project:". The prefix must start with one of
+// CacheKeyPrefixes. A zero TTL stops those keys being cached.
+func (c *Client) SetCacheTTL(prefix string, ttl time.Duration) error {
+	switch {
+	case strings.HasPrefix(prefix, "projects:"):
+		c.projectCache.SetPrefixTTL(prefix, ttl)
+	case strings.HasPrefix(prefix, "tasks:"):
+		c.taskCache.SetPrefixTTL(prefix, ttl)
+	case strings.HasPrefix(prefix, "tags:"):
+		c.tagCache.SetPrefixTTL(prefix, ttl)
+	default:
+		return fmt.Errorf("unknown cache key prefix %q (must start with one of %s): %w", prefix, strings.Join(CacheKeyPrefixes, ", "), ErrInvalidArgument)
+	}
+	return nil
+}
+
+// CacheStats returns statistics for the project, task and tag caches, keyed
+// "projects", "tasks" and "tags"
+func (c *Client) CacheStats() map[string]CacheStats {
+	return map[string]CacheStats{
+		"projects": c.projectCache.Stats(),
+		"tasks":    c.taskCache.Stats(),
+		"tags":     c.tagCache.Stats(),
+	}
+}
+
+// ClearCache drops cached lists whose keys start with prefix, or every
+// cached list if prefix is empty. Use it after editing OmniFocus directly.
+// Clearing tasks also empties the incremental sync replica, so they are
+// read in full again.
+func (c *Client) ClearCache(prefix string) error {
+	// Clearing says nothing about what changed, so every item of the
+	// cleared kinds may have changed, and items may have been created or
+	// deleted
+	ch := Change{ListChanged: true}
+	switch {
+	case prefix == "":
+		c.projectCache.InvalidateAll()
+		c.taskCache.InvalidateAll()
+		c.tagCache.InvalidateAll()
+		c.resetReplica()
+		ch.Tasks, ch.Projects, ch.Tags = true, true, true
+	case strings.HasPrefix(prefix, "projects:"):
+		c.projectCache.InvalidatePattern(prefix)
+		ch.Projects = true
+	case strings.HasPrefix(prefix, "tasks:"):
+		c.taskCache.InvalidatePattern(prefix)
+		c.resetReplica()
+		ch.Tasks = true
+	case strings.HasPrefix(prefix, "tags:"):
+		c.tagCache.InvalidatePattern(prefix)
+		ch.Tags = true
+	default:
+		return fmt.Errorf("unknown cache key prefix %q (must start with one of %s): %w", prefix, strings.Join(CacheKeyPrefixes, ", "), ErrInvalidArgument)
+	}
+	c.notifyChange(ch)
+	return nil
+}
+
+// Change describes what changed in OmniFocus, as far as the client knows
+type Change struct {
+	// TaskIDs and ProjectIDs are the items known to have changed
+	TaskIDs    []string
+	ProjectIDs []string
+	// Tasks, Projects and Tags mean any item of that kind may have changed,
+	// e.g. after a change made outside the server
+	Tasks    bool
+	Projects bool
+	Tags     bool
+	// ListChanged means items may have been created or deleted rather than
+	// just edited
+	ListChanged bool
+}
+
+// taskChange describes a write to taskID. The task's project comes from the
+// script result, or projectID if the result has no task; if neither is
+// known, any project may have changed.
+func taskChange(result *OperationResult, taskID, projectID string, created bool) Change {
+	ch := Change{TaskIDs: []string{taskID}, ListChanged: created}
+	switch {
+	case result.Task != nil && result.Task.ContainingProjectID != nil:
+		ch.ProjectIDs = []string{*result.Task.ContainingProjectID}
+	case result.Task != nil:
+		// An inbox task
+	case projectID != "":
+		ch.ProjectIDs = []string{projectID}
+	case !created:
+		ch.Projects = true
+	}
+	return ch
+}
+
+// OnChange registers fn to be called after the client changes OmniFocus or
+// learns that its cached data is out of date: after a write, a cache clear
+// or a change detected outside the server. fn runs on the goroutine that
+// saw the change and must not block.
+func (c *Client) OnChange(fn func(Change)) {
+	c.listenersMu.Lock()
+	defer c.listenersMu.Unlock()
+
+	c.listeners = append(c.listeners, fn)
+}
+
+// notifyChange calls the functions registered with OnChange
+func (c *Client) notifyChange(ch Change) {
+	c.listenersMu.Lock()
+	listeners := c.listeners
+	c.listenersMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(ch)
+	}
+}
+
+// SetDiskCache persists cached lists in dir so they survive restarts. Use
+// DefaultDiskCacheDir for the standard location.
+func (c *Client) SetDiskCache(dir string) error {
+	projects, err := NewDiskCache(filepath.Join(dir, "projects"))
+	if err != nil {
+		return err
+	}
+	tasks, err := NewDiskCache(filepath.Join(dir, "tasks"))
+	if err != nil {
+		return err
+	}
+	tags, err := NewDiskCache(filepath.Join(dir, "tags"))
+	if err != nil {
+		return err
+	}
+	c.projectCache.SetDisk(projects)
+	c.taskCache.SetDisk(tasks)
+	c.tagCache.SetDisk(tags)
+	return nil
+}
+
+// SchedulerStats returns the number of queued and running script executions
+func (c *Client) SchedulerStats() SchedulerStats {
+	if c.scheduler == nil {
+		return SchedulerStats{}
+	}
+	return c.scheduler.Stats()
+}
+
+// readOperations lists the operations that do not modify OmniFocus and may
+// run concurrently with each other
+var readOperations = map[string]bool{
+	"list_projects":  true,
+	"list_tasks":     true,
+	"list_tags":      true,
+	"database_state": true,
+}
+
+// operationName returns the operation name for a script, e.g. "list_tasks"
+func operationName(scriptName string) string {
+	return strings.TrimSuffix(scriptName, ".jxa")
+}
+
+// timeoutFor returns the timeout that applies to a script
+func (c *Client) timeoutFor(scriptName string) time.Duration {
+	if timeout, ok := c.operationTimeouts[operationName(scriptName)]; ok {
+		return timeout
+	}
+	return c.timeout
+}
+
 // findScriptsDir attempts to locate the scripts directory in multiple locations
 func findScriptsDir() string {
 	// Enable debug logging with MCP_OMNIFOCUS_DEBUG=1
@@ -206,125 +473,212 @@ func isValidScriptsDir(dir string) bool {
 	return false
 }
 
-// executeJXA executes a JXA script and returns the output.
-// If c.executor is set it is used instead of osascript (useful in tests).
-func (c *Client) executeJXA(scriptName string, args ...string) ([]byte, error) {
-	if c.executor != nil {
-		return c.executor(scriptName, args...)
+// executeJXA executes a JXA script and returns the output. Reads and
+// idempotent writes that fail with a transient error are retried according
+// to the client's retry policy.
+func (c *Client) executeJXA(ctx context.Context, scriptName string, args ...string) ([]byte, error) {
+	attempts := 1
+	if idempotentOperations[operationName(scriptName)] && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
 	}
 
-	scriptPath := filepath.Join(c.scriptsDir, scriptName)
+	for attempt := 1; ; attempt++ {
+		output, err := c.executeOnce(ctx, scriptName, args...)
+		if err == nil || attempt >= attempts || !IsTransient(err) {
+			return output, err
+		}
 
-	// Build command arguments: -l JavaScript, script path, then script arguments
-	cmdArgs := append([]string{"-l", "JavaScript", scriptPath}, args...)
-	cmd := exec.Command("osascript", cmdArgs...)
+		delay := c.retry.backoff(attempt)
+		log.Printf("Retrying %s in %s after transient error (attempt %d of %d): %v",
+			operationName(scriptName), delay, attempt, attempts, err)
+		ReportProgress(ctx, "Retrying %s in %s after a transient error", operationName(scriptName), delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, fmt.Errorf("%s cancelled: %w", scriptName, err)
+		}
+	}
+}
+
+// executeOnce executes a JXA script a single time once the scheduler admits
+// it. If c.executor is set it is used instead of osascript (useful in tests).
+// The script is killed when ctx is cancelled or its timeout elapses; time
+// spent queued does not count towards the timeout.
+func (c *Client) executeOnce(ctx context.Context, scriptName string, args ...string) ([]byte, error) {
+	if c.scheduler != nil {
+		release, err := c.scheduler.Acquire(ctx, !readOperations[operationName(scriptName)])
+		if err != nil {
+			return nil, fmt.Errorf("%s cancelled while queued: %w", scriptName, err)
+		}
+		defer release()
+	}
 
-	output, err := cmd.CombinedOutput()
+	timeout := c.timeoutFor(scriptName)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	run := c.executor
+	if run == nil {
+		run = c.runOsascript
+	}
+	ReportProgress(ctx, "Launching osascript for %s", operationName(scriptName))
+
+	output, err := run(ctx, scriptName, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute %s: %w - %s", scriptName, err, string(output))
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return nil, &TimeoutError{Operation: operationName(scriptName), Timeout: timeout}
+		case context.Canceled:
+			return nil, fmt.Errorf("%s cancelled: %w", scriptName, ctx.Err())
+		}
+		return nil, err
 	}
 
 	return output, nil
 }
 
-// ListProjects retrieves all projects from OmniFocus
-func (c *Client) ListProjects() ([]Project, error) {
-	cacheKey := "projects:all"
+// runOsascript runs a script from the scripts directory with osascript
+func (c *Client) runOsascript(ctx context.Context, scriptName string, args ...string) ([]byte, error) {
+	scriptPath := filepath.Join(c.scriptsDir, scriptName)
 
-	// Check cache first
-	if cached, found := c.cache.Get(cacheKey); found {
-		return cached.([]Project), nil
+	// Build command arguments: -l JavaScript, script path, then script arguments
+	cmdArgs := append([]string{"-l", "JavaScript", scriptPath}, args...)
+	cmd := exec.CommandContext(ctx, "osascript", cmdArgs...)
+	configureProcessGroup(cmd)
+	if c.timeZone != "" {
+		cmd.Env = append(os.Environ(), "TZ="+c.timeZone)
 	}
 
-	// Cache miss - fetch from OmniFocus
-	output, err := c.executeJXA("list_projects.jxa")
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err := cmd.Start()
+	if err == nil {
+		ReportProgress(ctx, "Reading from OmniFocus")
+		err = cmd.Wait()
+	}
+	output := buf.Bytes()
 	if err != nil {
-		return nil, err
+		return nil, &Error{
+			Code:      classifyOsascriptError(string(output)),
+			Operation: operationName(scriptName),
+			Message:   fmt.Sprintf("failed to execute %s: %v - %s", scriptName, err, strings.TrimSpace(string(output))),
+		}
 	}
 
-	var projects []Project
-	if err := json.Unmarshal(output, &projects); err != nil {
-		return nil, fmt.Errorf("failed to parse projects: %w", err)
-	}
+	return output, nil
+}
 
-	// Store in cache
-	c.cache.Set(cacheKey, projects)
+// ListProjects retrieves all projects from OmniFocus
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	return cachedFetch(ctx, c.projectCache, "projects:all", func(ctx context.Context) ([]Project, error) {
+		output, err := c.executeJXA(ctx, "list_projects.jxa")
+		if err != nil {
+			return nil, err
+		}
+		if err := checkScriptError("list_projects", output); err != nil {
+			return nil, err
+		}
 
-	return projects, nil
+		ReportProgress(ctx, "Parsing projects (%s)", byteSize(len(output)))
+		var projects []Project
+		if err := json.Unmarshal(output, &projects); err != nil {
+			return nil, fmt.Errorf("failed to parse projects: %w", err)
+		}
+		return projects, nil
+	})
 }
 
 // ListTasks retrieves tasks from OmniFocus, optionally filtered by project ID
-func (c *Client) ListTasks(projectID string) ([]Task, error) {
+func (c *Client) ListTasks(ctx context.Context, projectID string) ([]Task, error) {
 	// Create cache key based on whether we're filtering by project
 	cacheKey := "tasks:all"
 	if projectID != "" {
 		cacheKey = "tasks:project:" + projectID
 	}
 
-	// Check cache first
-	if cached, found := c.cache.Get(cacheKey); found {
-		return cached.([]Task), nil
-	}
-
-	// Cache miss - fetch from OmniFocus
-	var output []byte
-	var err error
-
-	if projectID != "" {
-		output, err = c.executeJXA("list_tasks.jxa", projectID)
-	} else {
-		output, err = c.executeJXA("list_tasks.jxa")
-	}
+	return cachedFetch(ctx, c.taskCache, cacheKey, func(ctx context.Context) ([]Task, error) {
+		if c.replica != nil {
+			return c.syncTasks(ctx, projectID)
+		}
+		if projectID != "" {
+			return c.fetchTasks(ctx, projectID)
+		}
+		return c.fetchTasks(ctx)
+	})
+}
 
+// fetchTasks runs list_tasks.jxa with args and parses the full task list
+func (c *Client) fetchTasks(ctx context.Context, args ...string) ([]Task, error) {
+	output, err := c.executeJXA(ctx, "list_tasks.jxa", args...)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkScriptError("list_tasks", output); err != nil {
+		return nil, err
+	}
 
+	ReportProgress(ctx, "Parsing tasks (%s)", byteSize(len(output)))
 	var tasks []Task
 	if err := json.Unmarshal(output, &tasks); err != nil {
 		return nil, fmt.Errorf("failed to parse tasks: %w", err)
 	}
-
-	// Store in cache
-	c.cache.Set(cacheKey, tasks)
-
 	return tasks, nil
 }
 
 // ListTags retrieves all tags from OmniFocus
-func (c *Client) ListTags() ([]Tag, error) {
-	cacheKey := "tags:all"
-
-	// Check cache first
-	if cached, found := c.cache.Get(cacheKey); found {
-		return cached.([]Tag), nil
-	}
+func (c *Client) ListTags(ctx context.Context) ([]Tag, error) {
+	return cachedFetch(ctx, c.tagCache, "tags:all", func(ctx context.Context) ([]Tag, error) {
+		output, err := c.executeJXA(ctx, "list_tags.jxa")
+		if err != nil {
+			return nil, err
+		}
+		if err := checkScriptError("list_tags", output); err != nil {
+			return nil, err
+		}
 
-	// Cache miss - fetch from OmniFocus
-	output, err := c.executeJXA("list_tags.jxa")
-	if err != nil {
-		return nil, err
-	}
+		ReportProgress(ctx, "Parsing tags (%s)", byteSize(len(output)))
+		var tags []Tag
+		if err := json.Unmarshal(output, &tags); err != nil {
+			return nil, fmt.Errorf("failed to parse tags: %w", err)
+		}
+		return tags, nil
+	})
+}
 
-	var tags []Tag
-	if err := json.Unmarshal(output, &tags); err != nil {
-		return nil, fmt.Errorf("failed to parse tags: %w", err)
+// cachedFetch returns the cached value for cacheKey, or loads it with fn on
+// a miss. A stale value is returned at once while it is refreshed in the
+// background.
+func cachedFetch[V any](ctx context.Context, cache *Cache[V], cacheKey string, fn func(context.Context) (V, error)) (V, error) {
+	if cached, age, stale, found := cache.Lookup(cacheKey); found {
+		recordFreshness(ctx, age, stale)
+		if stale {
+			go refresh(cache, cacheKey, fn)
+		}
+		return cached, nil
 	}
 
-	// Store in cache
-	c.cache.Set(cacheKey, tags)
+	// Cache miss - fetch from OmniFocus, sharing any fetch already running
+	return cache.Fetch(ctx, cacheKey, fn)
+}
 
-	return tags, nil
+// refresh reloads a stale cache entry. Concurrent refreshes of the same key
+// share one fetch.
+func refresh[V any](cache *Cache[V], cacheKey string, fn func(context.Context) (V, error)) {
+	if _, err := cache.Fetch(context.Background(), cacheKey, fn); err != nil {
+		log.Printf("Background refresh of %s failed: %v", cacheKey, err)
+	}
 }
 
 // CreateTask creates a new task in OmniFocus
-func (c *Client) CreateTask(req CreateTaskRequest) (*OperationResult, error) {
+func (c *Client) CreateTask(ctx context.Context, req CreateTaskRequest) (*OperationResult, error) {
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	output, err := c.executeJXA("create_task.jxa", string(reqJSON))
+	output, err := c.executeJXA(ctx, "create_task.jxa", string(reqJSON))
 	if err != nil {
 		return nil, err
 	}
@@ -335,27 +689,25 @@ func (c *Client) CreateTask(req CreateTaskRequest) (*OperationResult, error) {
 	}
 
 	if result.Error != "" {
-		return &result, fmt.Errorf("OmniFocus error: %s", result.Error)
+		return &result, newScriptError("create_task", result.Error, result.Code)
 	}
 
-	// Invalidate task caches since we created a new task
-	c.cache.InvalidatePattern("tasks:")
-	// If task was added to a specific project, also invalidate project cache
-	if req.ProjectID != "" {
-		c.cache.InvalidatePattern("projects:")
-	}
+	// Add the new task to the cached lists. An inbox task does not change
+	// any project.
+	c.patchTaskLists(&result, true, req.ProjectID != "")
+	c.notifyChange(taskChange(&result, result.ID, req.ProjectID, true))
 
 	return &result, nil
 }
 
 // CreateProject creates a new project in OmniFocus
-func (c *Client) CreateProject(req CreateProjectRequest) (*OperationResult, error) {
+func (c *Client) CreateProject(ctx context.Context, req CreateProjectRequest) (*OperationResult, error) {
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	output, err := c.executeJXA("create_project.jxa", string(reqJSON))
+	output, err := c.executeJXA(ctx, "create_project.jxa", string(reqJSON))
 	if err != nil {
 		return nil, err
 	}
@@ -366,23 +718,29 @@ func (c *Client) CreateProject(req CreateProjectRequest) (*OperationResult, erro
 	}
 
 	if result.Error != "" {
-		return &result, fmt.Errorf("OmniFocus error: %s", result.Error)
+		return &result, newScriptError("create_project", result.Error, result.Code)
 	}
 
-	// Invalidate project cache since we created a new project
-	c.cache.InvalidatePattern("projects:")
+	// Add the new project to the cached list
+	c.patchProjectLists(result.Project, true)
+	c.notifyChange(Change{ProjectIDs: []string{result.ID}, ListChanged: true})
 
 	return &result, nil
 }
 
 // UpdateTask updates an existing task in OmniFocus
-func (c *Client) UpdateTask(req UpdateTaskRequest) (*OperationResult, error) {
+func (c *Client) UpdateTask(ctx context.Context, req UpdateTaskRequest) (*OperationResult, error) {
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	output, err := c.executeJXA("update_task.jxa", string(reqJSON))
+	// Completing a repeating task is not idempotent, so it is not retried
+	run := c.executeJXA
+	if req.Completed != nil && *req.Completed {
+		run = c.executeOnce
+	}
+	output, err := run(ctx, "update_task.jxa", string(reqJSON))
 	if err != nil {
 		return nil, err
 	}
@@ -393,20 +751,20 @@ func (c *Client) UpdateTask(req UpdateTaskRequest) (*OperationResult, error) {
 	}
 
 	if result.Error != "" {
-		return &result, fmt.Errorf("OmniFocus error: %s", result.Error)
+		return &result, newScriptError("update_task", result.Error, result.Code)
 	}
 
-	// Invalidate task caches since we updated a task
-	c.cache.InvalidatePattern("tasks:")
-	// Also invalidate project cache in case task counts changed
-	c.cache.InvalidatePattern("projects:")
+	// Apply the updated task to the cached lists, including the task counts
+	// of its project
+	c.patchTaskLists(&result, false, true)
+	c.notifyChange(taskChange(&result, req.ID, "", false))
 
 	return &result, nil
 }
 
 // CompleteTask marks a task as complete in OmniFocus
-func (c *Client) CompleteTask(taskID string) (*OperationResult, error) {
-	output, err := c.executeJXA("complete_task.jxa", taskID)
+func (c *Client) CompleteTask(ctx context.Context, taskID string) (*OperationResult, error) {
+	output, err := c.executeJXA(ctx, "complete_task.jxa", taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -417,13 +775,13 @@ func (c *Client) CompleteTask(taskID string) (*OperationResult, error) {
 	}
 
 	if result.Error != "" {
-		return &result, fmt.Errorf("OmniFocus error: %s", result.Error)
+		return &result, newScriptError("complete_task", result.Error, result.Code)
 	}
 
-	// Invalidate task caches since we completed a task
-	c.cache.InvalidatePattern("tasks:")
-	// Also invalidate project cache in case task counts changed
-	c.cache.InvalidatePattern("projects:")
+	// Apply the completed task to the cached lists, including the task
+	// counts of its project
+	c.patchTaskLists(&result, false, true)
+	c.notifyChange(taskChange(&result, taskID, "", false))
 
 	return &result, nil
 }
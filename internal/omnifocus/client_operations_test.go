@@ -1,8 +1,12 @@
 package omnifocus
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -11,14 +15,18 @@ import (
 // provided function, so tests never call osascript.
 func newTestClient(executor func(string, ...string) ([]byte, error)) *Client {
 	c := NewClientWithCache("/fake/scripts", 30*time.Second)
-	c.executor = executor
+	c.executor = func(_ context.Context, script string, args ...string) ([]byte, error) {
+		return executor(script, args...)
+	}
 	return c
 }
 
 // newNoCacheTestClient creates a Client with caching disabled.
 func newNoCacheTestClient(executor func(string, ...string) ([]byte, error)) *Client {
 	c := NewClientWithCache("/fake/scripts", 0)
-	c.executor = executor
+	c.executor = func(_ context.Context, script string, args ...string) ([]byte, error) {
+		return executor(script, args...)
+	}
 	return c
 }
 
@@ -35,6 +43,7 @@ func mustJSON(v interface{}) []byte {
 // ---------- ListProjects ----------
 
 func TestListProjects_Success(t *testing.T) {
+	ctx := context.Background()
 	want := []Project{
 		{ID: "p1", Name: "Alpha", Status: "active"},
 		{ID: "p2", Name: "Beta", Status: "on-hold"},
@@ -46,7 +55,7 @@ func TestListProjects_Success(t *testing.T) {
 		return mustJSON(want), nil
 	})
 
-	got, err := c.ListProjects()
+	got, err := c.ListProjects(ctx)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -56,6 +65,7 @@ func TestListProjects_Success(t *testing.T) {
 }
 
 func TestListProjects_CacheHit(t *testing.T) {
+	ctx := context.Background()
 	calls := 0
 	projects := []Project{{ID: "p1", Name: "Alpha"}}
 	c := newTestClient(func(string, ...string) ([]byte, error) {
@@ -63,8 +73,8 @@ func TestListProjects_CacheHit(t *testing.T) {
 		return mustJSON(projects), nil
 	})
 
-	c.ListProjects()
-	c.ListProjects()
+	c.ListProjects(ctx)
+	c.ListProjects(ctx)
 
 	if calls != 1 {
 		t.Errorf("expected 1 executor call (cache hit), got %d", calls)
@@ -72,22 +82,24 @@ func TestListProjects_CacheHit(t *testing.T) {
 }
 
 func TestListProjects_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("osascript failed")
 	})
 
-	_, err := c.ListProjects()
+	_, err := c.ListProjects(ctx)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 }
 
 func TestListProjects_BadJSON(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return []byte("not json"), nil
 	})
 
-	_, err := c.ListProjects()
+	_, err := c.ListProjects(ctx)
 	if err == nil {
 		t.Fatal("expected parse error, got nil")
 	}
@@ -96,6 +108,7 @@ func TestListProjects_BadJSON(t *testing.T) {
 // ---------- ListTasks ----------
 
 func TestListTasks_All(t *testing.T) {
+	ctx := context.Background()
 	tasks := []Task{{ID: "t1", Name: "Task one"}, {ID: "t2", Name: "Task two"}}
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if len(args) != 0 {
@@ -104,7 +117,7 @@ func TestListTasks_All(t *testing.T) {
 		return mustJSON(tasks), nil
 	})
 
-	got, err := c.ListTasks("")
+	got, err := c.ListTasks(ctx, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -114,6 +127,7 @@ func TestListTasks_All(t *testing.T) {
 }
 
 func TestListTasks_ByProject(t *testing.T) {
+	ctx := context.Background()
 	tasks := []Task{{ID: "t1", Name: "Project task"}}
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if len(args) != 1 || args[0] != "proj-123" {
@@ -122,40 +136,42 @@ func TestListTasks_ByProject(t *testing.T) {
 		return mustJSON(tasks), nil
 	})
 
-	got, err := c.ListTasks("proj-123")
+	got, err := c.ListTasks(ctx, "proj-123")
 	if err != nil || len(got) != 1 {
 		t.Fatalf("got err=%v, len=%d", err, len(got))
 	}
 }
 
 func TestListTasks_CacheKeysDiffer(t *testing.T) {
+	ctx := context.Background()
 	calls := 0
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		calls++
 		return mustJSON([]Task{}), nil
 	})
 
-	c.ListTasks("")
-	c.ListTasks("proj-1")
-	c.ListTasks("proj-2")
+	c.ListTasks(ctx, "")
+	c.ListTasks(ctx, "proj-1")
+	c.ListTasks(ctx, "proj-2")
 	// All tasks + two project caches = 3 distinct keys, all cache misses
 	if calls != 3 {
 		t.Errorf("expected 3 executor calls, got %d", calls)
 	}
 
 	// Repeat — all should hit cache
-	c.ListTasks("")
-	c.ListTasks("proj-1")
+	c.ListTasks(ctx, "")
+	c.ListTasks(ctx, "proj-1")
 	if calls != 3 {
 		t.Errorf("expected still 3 after cache hits, got %d", calls)
 	}
 }
 
 func TestListTasks_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("fail")
 	})
-	_, err := c.ListTasks("")
+	_, err := c.ListTasks(ctx, "")
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -164,6 +180,7 @@ func TestListTasks_ExecutorError(t *testing.T) {
 // ---------- ListTags ----------
 
 func TestListTags_Success(t *testing.T) {
+	ctx := context.Background()
 	tags := []Tag{{ID: "tag1", Name: "home"}, {ID: "tag2", Name: "work"}}
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if script != "list_tags.jxa" {
@@ -172,30 +189,32 @@ func TestListTags_Success(t *testing.T) {
 		return mustJSON(tags), nil
 	})
 
-	got, err := c.ListTags()
+	got, err := c.ListTags(ctx)
 	if err != nil || len(got) != 2 {
 		t.Fatalf("got err=%v len=%d", err, len(got))
 	}
 }
 
 func TestListTags_CacheHit(t *testing.T) {
+	ctx := context.Background()
 	calls := 0
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		calls++
 		return mustJSON([]Tag{}), nil
 	})
-	c.ListTags()
-	c.ListTags()
+	c.ListTags(ctx)
+	c.ListTags(ctx)
 	if calls != 1 {
 		t.Errorf("expected 1 call, got %d", calls)
 	}
 }
 
 func TestListTags_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("fail")
 	})
-	_, err := c.ListTags()
+	_, err := c.ListTags(ctx)
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -204,6 +223,7 @@ func TestListTags_ExecutorError(t *testing.T) {
 // ---------- CreateTask ----------
 
 func TestCreateTask_Inbox(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if script != "create_task.jxa" {
 			t.Errorf("unexpected script %s", script)
@@ -218,13 +238,14 @@ func TestCreateTask_Inbox(t *testing.T) {
 		return mustJSON(OperationResult{ID: "new-task-id", Name: req.Name, Success: true}), nil
 	})
 
-	result, err := c.CreateTask(CreateTaskRequest{Name: "Buy milk"})
+	result, err := c.CreateTask(ctx, CreateTaskRequest{Name: "Buy milk"})
 	if err != nil || !result.Success || result.ID != "new-task-id" {
 		t.Fatalf("err=%v result=%+v", err, result)
 	}
 }
 
 func TestCreateTask_WithProject_InvalidatesProjectCache(t *testing.T) {
+	ctx := context.Background()
 	// Pre-populate project cache
 	projects := []Project{{ID: "p1", Name: "Work"}}
 	projCalls := 0
@@ -239,9 +260,9 @@ func TestCreateTask_WithProject_InvalidatesProjectCache(t *testing.T) {
 		return nil, errors.New("unexpected script")
 	})
 
-	c.ListProjects() // populates cache (projCalls = 1)
-	c.CreateTask(CreateTaskRequest{Name: "Task", ProjectID: "p1"})
-	c.ListProjects() // cache was invalidated, should re-fetch (projCalls = 2)
+	c.ListProjects(ctx) // populates cache (projCalls = 1)
+	c.CreateTask(ctx, CreateTaskRequest{Name: "Task", ProjectID: "p1"})
+	c.ListProjects(ctx) // cache was invalidated, should re-fetch (projCalls = 2)
 
 	if projCalls != 2 {
 		t.Errorf("expected 2 project fetches, got %d", projCalls)
@@ -249,6 +270,7 @@ func TestCreateTask_WithProject_InvalidatesProjectCache(t *testing.T) {
 }
 
 func TestCreateTask_InboxDoesNotInvalidateProjectCache(t *testing.T) {
+	ctx := context.Background()
 	projCalls := 0
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		switch script {
@@ -261,9 +283,9 @@ func TestCreateTask_InboxDoesNotInvalidateProjectCache(t *testing.T) {
 		return nil, errors.New("unexpected script")
 	})
 
-	c.ListProjects() // projCalls = 1
-	c.CreateTask(CreateTaskRequest{Name: "Inbox task"})
-	c.ListProjects() // should hit cache (projCalls stays 1)
+	c.ListProjects(ctx) // projCalls = 1
+	c.CreateTask(ctx, CreateTaskRequest{Name: "Inbox task"})
+	c.ListProjects(ctx) // should hit cache (projCalls stays 1)
 
 	if projCalls != 1 {
 		t.Errorf("expected 1 project fetch, got %d", projCalls)
@@ -271,27 +293,30 @@ func TestCreateTask_InboxDoesNotInvalidateProjectCache(t *testing.T) {
 }
 
 func TestCreateTask_OmniFocusError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return mustJSON(OperationResult{Error: "Project not found"}), nil
 	})
 
-	_, err := c.CreateTask(CreateTaskRequest{Name: "Test", ProjectID: "bad-id"})
+	_, err := c.CreateTask(ctx, CreateTaskRequest{Name: "Test", ProjectID: "bad-id"})
 	if err == nil {
 		t.Fatal("expected OmniFocus error")
 	}
 }
 
 func TestCreateTask_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("osascript fail")
 	})
-	_, err := c.CreateTask(CreateTaskRequest{Name: "Test"})
+	_, err := c.CreateTask(ctx, CreateTaskRequest{Name: "Test"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
 
 func TestCreateTask_InvalidatesTaskCache(t *testing.T) {
+	ctx := context.Background()
 	taskCalls := 0
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		switch script {
@@ -304,9 +329,9 @@ func TestCreateTask_InvalidatesTaskCache(t *testing.T) {
 		return nil, errors.New("unexpected script")
 	})
 
-	c.ListTasks("") // taskCalls = 1
-	c.CreateTask(CreateTaskRequest{Name: "New task"})
-	c.ListTasks("") // invalidated, taskCalls = 2
+	c.ListTasks(ctx, "") // taskCalls = 1
+	c.CreateTask(ctx, CreateTaskRequest{Name: "New task"})
+	c.ListTasks(ctx, "") // invalidated, taskCalls = 2
 
 	if taskCalls != 2 {
 		t.Errorf("expected 2 task fetches, got %d", taskCalls)
@@ -316,6 +341,7 @@ func TestCreateTask_InvalidatesTaskCache(t *testing.T) {
 // ---------- CreateProject ----------
 
 func TestCreateProject_Success(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if script != "create_project.jxa" {
 			t.Errorf("unexpected script %s", script)
@@ -328,13 +354,14 @@ func TestCreateProject_Success(t *testing.T) {
 		return mustJSON(OperationResult{ID: "proj-1", Name: req.Name, Success: true}), nil
 	})
 
-	result, err := c.CreateProject(CreateProjectRequest{Name: "New Project"})
+	result, err := c.CreateProject(ctx, CreateProjectRequest{Name: "New Project"})
 	if err != nil || !result.Success {
 		t.Fatalf("err=%v result=%+v", err, result)
 	}
 }
 
 func TestCreateProject_WithNote(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(_ string, args ...string) ([]byte, error) {
 		var req CreateProjectRequest
 		json.Unmarshal([]byte(args[0]), &req)
@@ -343,10 +370,11 @@ func TestCreateProject_WithNote(t *testing.T) {
 		}
 		return mustJSON(OperationResult{ID: "p1", Name: req.Name, Success: true}), nil
 	})
-	c.CreateProject(CreateProjectRequest{Name: "P", Note: "My note"})
+	c.CreateProject(ctx, CreateProjectRequest{Name: "P", Note: "My note"})
 }
 
 func TestCreateProject_InvalidatesProjectCache(t *testing.T) {
+	ctx := context.Background()
 	projCalls := 0
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		switch script {
@@ -359,9 +387,9 @@ func TestCreateProject_InvalidatesProjectCache(t *testing.T) {
 		return nil, errors.New("unexpected")
 	})
 
-	c.ListProjects() // projCalls = 1
-	c.CreateProject(CreateProjectRequest{Name: "P"})
-	c.ListProjects() // invalidated, projCalls = 2
+	c.ListProjects(ctx) // projCalls = 1
+	c.CreateProject(ctx, CreateProjectRequest{Name: "P"})
+	c.ListProjects(ctx) // invalidated, projCalls = 2
 
 	if projCalls != 2 {
 		t.Errorf("expected 2, got %d", projCalls)
@@ -369,20 +397,22 @@ func TestCreateProject_InvalidatesProjectCache(t *testing.T) {
 }
 
 func TestCreateProject_OmniFocusError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return mustJSON(OperationResult{Error: "Name required"}), nil
 	})
-	_, err := c.CreateProject(CreateProjectRequest{})
+	_, err := c.CreateProject(ctx, CreateProjectRequest{})
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
 
 func TestCreateProject_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("fail")
 	})
-	_, err := c.CreateProject(CreateProjectRequest{Name: "P"})
+	_, err := c.CreateProject(ctx, CreateProjectRequest{Name: "P"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -391,6 +421,7 @@ func TestCreateProject_ExecutorError(t *testing.T) {
 // ---------- UpdateTask ----------
 
 func TestUpdateTask_Name(t *testing.T) {
+	ctx := context.Background()
 	newName := "Renamed task"
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if script != "update_task.jxa" {
@@ -404,13 +435,14 @@ func TestUpdateTask_Name(t *testing.T) {
 		return mustJSON(OperationResult{ID: "t1", Name: newName, Success: true}), nil
 	})
 
-	result, err := c.UpdateTask(UpdateTaskRequest{ID: "t1", Name: &newName})
+	result, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1", Name: &newName})
 	if err != nil || result.Name != newName {
 		t.Fatalf("err=%v result=%+v", err, result)
 	}
 }
 
 func TestUpdateTask_Flagged(t *testing.T) {
+	ctx := context.Background()
 	flagged := true
 	c := newTestClient(func(_ string, args ...string) ([]byte, error) {
 		var req UpdateTaskRequest
@@ -420,10 +452,24 @@ func TestUpdateTask_Flagged(t *testing.T) {
 		}
 		return mustJSON(OperationResult{ID: "t1", Name: "T", Success: true}), nil
 	})
-	c.UpdateTask(UpdateTaskRequest{ID: "t1", Flagged: &flagged})
+	c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1", Flagged: &flagged})
+}
+
+func TestUpdateTask_ClearDueDate(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(func(_ string, args ...string) ([]byte, error) {
+		if !strings.Contains(args[0], `"clearDueDate":true`) {
+			t.Errorf("expected the script to be told to clear the due date, got %s", args[0])
+		}
+		return mustJSON(OperationResult{ID: "t1", Name: "T", Success: true}), nil
+	})
+	if _, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1", ClearDueDate: true}); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestUpdateTask_InvalidatesTaskAndProjectCache(t *testing.T) {
+	ctx := context.Background()
 	taskCalls, projCalls := 0, 0
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		switch script {
@@ -439,11 +485,11 @@ func TestUpdateTask_InvalidatesTaskAndProjectCache(t *testing.T) {
 		return nil, errors.New("unexpected")
 	})
 
-	c.ListTasks("")    // taskCalls=1
-	c.ListProjects()  // projCalls=1
-	c.UpdateTask(UpdateTaskRequest{ID: "t1"})
-	c.ListTasks("")   // invalidated, taskCalls=2
-	c.ListProjects()  // invalidated, projCalls=2
+	c.ListTasks(ctx, "") // taskCalls=1
+	c.ListProjects(ctx)  // projCalls=1
+	c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1"})
+	c.ListTasks(ctx, "") // invalidated, taskCalls=2
+	c.ListProjects(ctx)  // invalidated, projCalls=2
 
 	if taskCalls != 2 || projCalls != 2 {
 		t.Errorf("tasks=%d projects=%d", taskCalls, projCalls)
@@ -451,20 +497,22 @@ func TestUpdateTask_InvalidatesTaskAndProjectCache(t *testing.T) {
 }
 
 func TestUpdateTask_OmniFocusError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return mustJSON(OperationResult{Error: "Task not found"}), nil
 	})
-	_, err := c.UpdateTask(UpdateTaskRequest{ID: "bad"})
+	_, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "bad"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
 
 func TestUpdateTask_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("fail")
 	})
-	_, err := c.UpdateTask(UpdateTaskRequest{ID: "t1"})
+	_, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -473,6 +521,7 @@ func TestUpdateTask_ExecutorError(t *testing.T) {
 // ---------- CompleteTask ----------
 
 func TestCompleteTask_Success(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		if script != "complete_task.jxa" {
 			t.Errorf("unexpected script %s", script)
@@ -483,13 +532,14 @@ func TestCompleteTask_Success(t *testing.T) {
 		return mustJSON(OperationResult{ID: "task-99", Name: "Done", Success: true}), nil
 	})
 
-	result, err := c.CompleteTask("task-99")
+	result, err := c.CompleteTask(ctx, "task-99")
 	if err != nil || !result.Success {
 		t.Fatalf("err=%v result=%+v", err, result)
 	}
 }
 
 func TestCompleteTask_InvalidatesTaskAndProjectCache(t *testing.T) {
+	ctx := context.Background()
 	taskCalls, projCalls := 0, 0
 	c := newTestClient(func(script string, args ...string) ([]byte, error) {
 		switch script {
@@ -505,11 +555,11 @@ func TestCompleteTask_InvalidatesTaskAndProjectCache(t *testing.T) {
 		return nil, errors.New("unexpected")
 	})
 
-	c.ListTasks("")
-	c.ListProjects()
-	c.CompleteTask("t1")
-	c.ListTasks("")
-	c.ListProjects()
+	c.ListTasks(ctx, "")
+	c.ListProjects(ctx)
+	c.CompleteTask(ctx, "t1")
+	c.ListTasks(ctx, "")
+	c.ListProjects(ctx)
 
 	if taskCalls != 2 || projCalls != 2 {
 		t.Errorf("tasks=%d projects=%d", taskCalls, projCalls)
@@ -517,20 +567,22 @@ func TestCompleteTask_InvalidatesTaskAndProjectCache(t *testing.T) {
 }
 
 func TestCompleteTask_OmniFocusError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return mustJSON(OperationResult{Error: "Task not found"}), nil
 	})
-	_, err := c.CompleteTask("bad-id")
+	_, err := c.CompleteTask(ctx, "bad-id")
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
 
 func TestCompleteTask_ExecutorError(t *testing.T) {
+	ctx := context.Background()
 	c := newTestClient(func(string, ...string) ([]byte, error) {
 		return nil, errors.New("fail")
 	})
-	_, err := c.CompleteTask("t1")
+	_, err := c.CompleteTask(ctx, "t1")
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -542,8 +594,8 @@ func TestExecuteJXA_NoExecutorUsesOsascriptPath(t *testing.T) {
 	// Without an executor set, executeJXA should build the osascript command.
 	// We verify it attempts to call the right script by checking the error
 	// message contains the script name (osascript won't be available in CI).
-	c := &Client{scriptsDir: "/fake/scripts", cache: NewCache(0)}
-	_, err := c.executeJXA("list_projects.jxa")
+	c := &Client{scriptsDir: "/fake/scripts"}
+	_, err := c.executeJXA(context.Background(), "list_projects.jxa")
 	// On Linux CI osascript doesn't exist — we just confirm no panic and an error.
 	if err == nil {
 		// On macOS with OmniFocus absent this may also error — either way we
@@ -551,3 +603,177 @@ func TestExecuteJXA_NoExecutorUsesOsascriptPath(t *testing.T) {
 		t.Log("executeJXA succeeded (OmniFocus available)")
 	}
 }
+
+// ---------- timeouts and cancellation ----------
+
+// blockingExecutor waits until the context is done, like a hung osascript.
+func blockingExecutor(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, errors.New("signal: killed")
+}
+
+func TestExecuteJXA_TimeoutReturnsTimeoutError(t *testing.T) {
+	c := NewClientWithCache("/fake/scripts", 0)
+	c.executor = blockingExecutor
+	c.SetTimeout(20 * time.Millisecond)
+
+	_, err := c.ListTasks(context.Background(), "")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if timeoutErr.Operation != "list_tasks" || timeoutErr.Timeout != 20*time.Millisecond {
+		t.Errorf("unexpected timeout error %+v", timeoutErr)
+	}
+}
+
+func TestExecuteJXA_OperationTimeoutOverridesDefault(t *testing.T) {
+	c := NewClientWithCache("/fake/scripts", 0)
+	c.executor = blockingExecutor
+	c.SetTimeout(time.Hour)
+	c.SetOperationTimeout("complete_task", 20*time.Millisecond)
+
+	_, err := c.CompleteTask(context.Background(), "t1")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 20*time.Millisecond {
+		t.Fatalf("expected 20ms TimeoutError, got %v", err)
+	}
+}
+
+func TestExecuteJXA_CallerCancellation(t *testing.T) {
+	c := NewClientWithCache("/fake/scripts", 0)
+	c.executor = blockingExecutor
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := c.ListProjects(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Error("cancellation must not be reported as a timeout")
+	}
+}
+
+// ---------- stale-while-revalidate ----------
+
+func TestListTasks_ServesStaleWhileRefreshing(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(func(string, ...string) ([]byte, error) {
+		n := calls.Add(1)
+		return mustJSON([]Task{{ID: fmt.Sprintf("v%d", n)}}), nil
+	})
+	c.taskCache = NewCache[[]Task](20 * time.Millisecond)
+	c.SetStaleTTL(time.Minute)
+
+	c.ListTasks(context.Background(), "")
+	time.Sleep(30 * time.Millisecond)
+
+	ctx, freshness := WithFreshness(context.Background())
+	tasks, err := c.ListTasks(ctx, "")
+	if err != nil || len(tasks) != 1 || tasks[0].ID != "v1" {
+		t.Fatalf("expected stale v1 at once, got %+v err=%v", tasks, err)
+	}
+	if !freshness.Stale() || freshness.Age() < 20*time.Millisecond {
+		t.Errorf("expected stale data at least 20ms old, got stale=%v age=%s", freshness.Stale(), freshness.Age())
+	}
+
+	// The background refresh replaces the stale entry
+	deadline := time.Now().Add(time.Second)
+	for {
+		if cached, found := c.taskCache.Get("tasks:all"); found && cached[0].ID == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale entry was not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, freshness = WithFreshness(context.Background())
+	tasks, _ = c.ListTasks(ctx, "")
+	if tasks[0].ID != "v2" || freshness.Stale() {
+		t.Errorf("expected fresh v2, got %+v stale=%v", tasks, freshness.Stale())
+	}
+}
+
+func TestListTasks_CallerMutationDoesNotCorruptCache(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(func(string, ...string) ([]byte, error) {
+		return mustJSON([]Task{{ID: "t1", Name: "Original", Tags: []string{"home"}}}), nil
+	})
+
+	first, _ := c.ListTasks(ctx, "")
+	first[0].Name = "Mutated"
+	first[0].Tags[0] = "work"
+
+	second, _ := c.ListTasks(ctx, "")
+	if second[0].Name != "Original" || second[0].Tags[0] != "home" {
+		t.Errorf("cached tasks were modified by a caller: %+v", second[0])
+	}
+}
+
+func TestSetCacheTTL_RoutesByPrefix(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	c := newTestClient(func(string, ...string) ([]byte, error) {
+		calls++
+		return mustJSON([]Tag{{ID: "tag1"}}), nil
+	})
+	if err := c.SetCacheTTL("tags:", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.ListTags(ctx)
+	c.ListTags(ctx)
+	if calls != 2 {
+		t.Errorf("expected tags not to be cached, got %d calls", calls)
+	}
+
+	if err := c.SetCacheTTL("folders:", time.Minute); err == nil {
+		t.Error("expected an error for an unknown prefix")
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	c := newTestClient(func(script string, _ ...string) ([]byte, error) {
+		calls++
+		if script == "list_tags.jxa" {
+			return mustJSON([]Tag{}), nil
+		}
+		return mustJSON([]Task{}), nil
+	})
+	c.ListTasks(ctx, "p1")
+	c.ListTasks(ctx, "p2")
+	c.ListTags(ctx)
+
+	if err := c.ClearCache("tasks:project:p1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.ListTasks(ctx, "p1")
+	c.ListTasks(ctx, "p2")
+	c.ListTags(ctx)
+	if calls != 4 {
+		t.Errorf("expected only p1 to be refetched, got %d calls", calls)
+	}
+
+	c.ClearCache("")
+	c.ListTags(ctx)
+	if calls != 5 {
+		t.Errorf("expected a full clear to drop tags, got %d calls", calls)
+	}
+
+	stats := c.CacheStats()
+	if stats["tasks"].Invalidations["tasks:project:p1"] != 1 || stats["tags"].Hits != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if err := c.ClearCache("folders:"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package omnifocus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// diskCacheVersion is bumped whenever the file layout or the cached types
+// change incompatibly; files from other versions are ignored.
+const diskCacheVersion = 1
+
+// DiskCache persists cache entries as JSON files so they survive server
+// restarts. Several server processes may share a directory: writers hold an
+// exclusive lock on the directory and readers a shared one.
+type DiskCache struct {
+	dir string
+}
+
+// diskEntry is the layout of a cache file
+type diskEntry struct {
+	Version   int             `json:"version"`
+	Key       string          `json:"key"`
+	StoredAt  time.Time       `json:"storedAt"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Checksum  string          `json:"checksum"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// DefaultDiskCacheDir returns the mcp-omnifocus directory under the user's
+// cache directory
+func DefaultDiskCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mcp-omnifocus"), nil
+}
+
+// NewDiskCache creates a disk cache in dir, creating the directory if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// Dir returns the directory the cache files are kept in
+func (d *DiskCache) Dir() string {
+	return d.dir
+}
+
+// path returns the file for key. Keys are hex encoded, so the files for a
+// key prefix share a file name prefix.
+func (d *DiskCache) path(key string) string {
+	return filepath.Join(d.dir, hex.EncodeToString([]byte(key))+".json")
+}
+
+// lock takes the directory lock and returns a function releasing it
+func (d *DiskCache) lock(exclusive bool) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(d.dir, ".lock"), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// Load reads the entry for key. Missing, corrupt and outdated files all
+// report found as false; corrupt and outdated files are removed.
+func (d *DiskCache) Load(key string) (data []byte, storedAt, expiresAt time.Time, found bool) {
+	unlock, err := d.lock(false)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, false
+	}
+	raw, err := os.ReadFile(d.path(key))
+	unlock()
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, false
+	}
+
+	var entry diskEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || !entry.valid(key) {
+		d.Remove(key)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	return entry.Data, entry.StoredAt, entry.ExpiresAt, true
+}
+
+// valid reports whether the entry is from this version, belongs to key and
+// matches its checksum
+func (e *diskEntry) valid(key string) bool {
+	return e.Version == diskCacheVersion && e.Key == key && e.Checksum == checksum(e.Data)
+}
+
+// Store writes the entry for key. The file is replaced atomically, so
+// readers never see a partial write.
+func (d *DiskCache) Store(key string, data []byte, storedAt, expiresAt time.Time) error {
+	raw, err := json.Marshal(diskEntry{
+		Version:   diskCacheVersion,
+		Key:       key,
+		StoredAt:  storedAt,
+		ExpiresAt: expiresAt,
+		Checksum:  checksum(data),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	unlock, err := d.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path(key))
+}
+
+// Remove deletes the entry for key
+func (d *DiskCache) Remove(key string) error {
+	unlock, err := d.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// RemovePrefix deletes the entries whose keys start with prefix
+func (d *DiskCache) RemovePrefix(prefix string) error {
+	return d.removeWhere(func(name string, _ []byte) bool {
+		return strings.HasPrefix(name, hex.EncodeToString([]byte(prefix)))
+	})
+}
+
+// RemoveStoredBefore deletes the entries whose keys start with prefix and
+// that were stored before t
+func (d *DiskCache) RemoveStoredBefore(prefix string, t time.Time) error {
+	return d.removeWhere(func(name string, raw []byte) bool {
+		if !strings.HasPrefix(name, hex.EncodeToString([]byte(prefix))) {
+			return false
+		}
+		var entry diskEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return true
+		}
+		return entry.StoredAt.Before(t)
+	})
+}
+
+// RemoveExpired deletes the entries that expired before cutoff, along with
+// any that are corrupt or outdated
+func (d *DiskCache) RemoveExpired(cutoff time.Time) error {
+	return d.removeWhere(func(_ string, raw []byte) bool {
+		var entry diskEntry
+		if err := json.Unmarshal(raw, &entry); err != nil || entry.Version != diskCacheVersion {
+			return true
+		}
+		return entry.ExpiresAt.Before(cutoff)
+	})
+}
+
+// removeWhere deletes the cache files for which match returns true. match
+// receives the file name and, if it was readable, its contents.
+func (d *DiskCache) removeWhere(match func(name string, raw []byte) bool) error {
+	unlock, err := d.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		raw, _ := os.ReadFile(filepath.Join(d.dir, name))
+		if match(name, raw) {
+			os.Remove(filepath.Join(d.dir, name))
+		}
+	}
+	return nil
+}
+
+// checksum returns the hex SHA-256 of data, ignoring insignificant
+// whitespace so a re-encoded entry still matches
+func checksum(data []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		compact.Reset()
+		compact.Write(data)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return hex.EncodeToString(sum[:])
+}
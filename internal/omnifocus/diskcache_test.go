@@ -0,0 +1,202 @@
+package omnifocus
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newDiskTestClient(t *testing.T, dir string, ttl time.Duration, executor func(string, ...string) ([]byte, error)) *Client {
+	t.Helper()
+	c := NewClientWithCache("/fake/scripts", ttl)
+	c.executor = func(_ context.Context, script string, args ...string) ([]byte, error) {
+		return executor(script, args...)
+	}
+	if err := c.SetDiskCache(dir); err != nil {
+		t.Fatalf("SetDiskCache: %v", err)
+	}
+	return c
+}
+
+func TestDiskCache_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	first := newDiskTestClient(t, dir, time.Minute, func(string, ...string) ([]byte, error) {
+		return mustJSON([]Task{{ID: "t1", Name: "Persisted"}}), nil
+	})
+	if _, err := first.ListTasks(context.Background(), "proj-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A new process with an empty memory cache reads the entry from disk
+	calls := 0
+	second := newDiskTestClient(t, dir, time.Minute, func(string, ...string) ([]byte, error) {
+		calls++
+		return mustJSON([]Task{}), nil
+	})
+	tasks, err := second.ListTasks(context.Background(), "proj-1")
+	if err != nil || len(tasks) != 1 || tasks[0].Name != "Persisted" {
+		t.Fatalf("expected persisted task, got %+v err=%v", tasks, err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no script run, got %d", calls)
+	}
+}
+
+func TestDiskCache_HonoursTTLAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	first := newDiskTestClient(t, dir, 20*time.Millisecond, func(string, ...string) ([]byte, error) {
+		return mustJSON([]Tag{{ID: "old"}}), nil
+	})
+	first.ListTags(context.Background())
+	time.Sleep(30 * time.Millisecond)
+
+	second := newDiskTestClient(t, dir, time.Minute, func(string, ...string) ([]byte, error) {
+		return mustJSON([]Tag{{ID: "new"}}), nil
+	})
+	tags, _ := second.ListTags(context.Background())
+	if len(tags) != 1 || tags[0].ID != "new" {
+		t.Errorf("expected expired entry to be refetched, got %+v", tags)
+	}
+}
+
+func TestDiskCache_RejectsCorruptAndOutdatedFiles(t *testing.T) {
+	disk, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	expires := now.Add(time.Minute)
+
+	tamper := func(key string, edit func(*diskEntry)) {
+		disk.Store(key, []byte(`[{"id":"t1"}]`), now, expires)
+		raw, _ := os.ReadFile(disk.path(key))
+		var entry diskEntry
+		json.Unmarshal(raw, &entry)
+		edit(&entry)
+		raw, _ = json.Marshal(entry)
+		os.WriteFile(disk.path(key), raw, 0o600)
+	}
+
+	tamper("tasks:all", func(e *diskEntry) { e.Data = json.RawMessage(`[{"id":"t2"}]`) })
+	if _, _, _, found := disk.Load("tasks:all"); found {
+		t.Error("expected checksum mismatch to be rejected")
+	}
+	if _, err := os.Stat(disk.path("tasks:all")); !os.IsNotExist(err) {
+		t.Error("expected corrupt file to be removed")
+	}
+
+	tamper("tags:all", func(e *diskEntry) { e.Version = diskCacheVersion + 1 })
+	if _, _, _, found := disk.Load("tags:all"); found {
+		t.Error("expected other schema version to be rejected")
+	}
+
+	os.WriteFile(disk.path("projects:all"), []byte("{not json"), 0o600)
+	if _, _, _, found := disk.Load("projects:all"); found {
+		t.Error("expected unparseable file to be rejected")
+	}
+}
+
+func TestDiskCache_InvalidationRemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := newDiskTestClient(t, dir, time.Minute, func(script string, _ ...string) ([]byte, error) {
+		if script == "complete_task.jxa" {
+			return mustJSON(OperationResult{ID: "t1", Success: true}), nil
+		}
+		return mustJSON([]Task{{ID: "t1"}}), nil
+	})
+	c.ListTasks(context.Background(), "")
+	c.ListTasks(context.Background(), "proj-1")
+	c.CompleteTask(context.Background(), "t1")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "tasks", "*.json"))
+	if len(files) != 0 {
+		t.Errorf("expected task files to be removed, found %v", files)
+	}
+}
+
+func TestDiskCache_ConcurrentAccess(t *testing.T) {
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		// Separate instances stand in for separate server processes
+		disk, err := NewDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				data, _ := json.Marshal([]Task{{ID: "t", Name: string(rune('a' + i))}})
+				if err := disk.Store("tasks:all", data, time.Now(), time.Now().Add(time.Minute)); err != nil {
+					t.Error(err)
+				}
+				if data, _, _, found := disk.Load("tasks:all"); found {
+					var tasks []Task
+					if err := json.Unmarshal(data, &tasks); err != nil || len(tasks) != 1 {
+						t.Errorf("read torn entry %q", data)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDiskCache_SlowDiskDoesNotBlockReads(t *testing.T) {
+	disk, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewCache[[]Task](time.Minute)
+	cache.SetDisk(disk)
+	cache.Set("tags:all", []Task{{ID: "g1"}})
+
+	// Another process holding the directory lock stalls disk changes
+	unlock, err := disk.lock(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make(chan struct{})
+	go func() {
+		cache.Set("tasks:all", []Task{{ID: "t1"}})
+		cache.Patch("tasks:", func(_ string, tasks []Task) ([]Task, bool) {
+			return append(tasks, Task{ID: "t2"}), true
+		})
+		close(stored)
+	}()
+	invalidated := make(chan struct{})
+	go func() {
+		cache.InvalidatePattern("projects:")
+		cache.Cleanup()
+		close(invalidated)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	read := make(chan bool)
+	go func() {
+		_, ok := cache.Get("tags:all")
+		read <- ok
+	}()
+	select {
+	case ok := <-read:
+		if !ok {
+			t.Error("expected the cached tags")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a read waited for the disk")
+	}
+
+	unlock()
+	<-stored
+	<-invalidated
+	data, _, _, found := disk.Load("tasks:all")
+	var tasks []Task
+	if !found || json.Unmarshal(data, &tasks) != nil || len(tasks) != 2 {
+		t.Errorf("expected the patched tasks on disk, got %s", data)
+	}
+}
@@ -0,0 +1,167 @@
+package omnifocus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for the failure classes callers can act on. Errors
+// returned by the client wrap one of these where the class is known, so use
+// errors.Is to test for them.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = errors.New("automation permission denied")
+	ErrAppNotRunning    = errors.New("OmniFocus is not running")
+	ErrTimeout          = errors.New("timed out")
+	ErrInvalidArgument  = errors.New("invalid argument")
+)
+
+// ErrorCode is a stable, machine-readable error classification. JXA scripts
+// report it in the "code" field of their error objects.
+type ErrorCode string
+
+// Error codes shared by the JXA scripts and MCP tool errors
+const (
+	CodeNotFound         ErrorCode = "NOT_FOUND"
+	CodePermissionDenied ErrorCode = "PERMISSION_DENIED"
+	CodeAppNotRunning    ErrorCode = "APP_NOT_RUNNING"
+	CodeTimeout          ErrorCode = "TIMEOUT"
+	CodeInvalidArgument  ErrorCode = "INVALID_ARGUMENT"
+	CodeOutOfScope       ErrorCode = "OUT_OF_SCOPE"
+	CodeReadOnly         ErrorCode = "READ_ONLY"
+	CodeInternal         ErrorCode = "INTERNAL"
+)
+
+// sentinels pairs codes with the sentinel errors they unwrap to. CodeOf
+// returns the first that matches, so an error joining several is
+// classified the same way every time, by the most decisive: a refusal
+// first, since retrying cannot help, then why OmniFocus could not run the
+// script, then what it reported.
+var sentinels = []struct {
+	code ErrorCode
+	err  error
+}{
+	{CodeReadOnly, ErrReadOnly},
+	{CodeOutOfScope, ErrOutOfScope},
+	{CodePermissionDenied, ErrPermissionDenied},
+	{CodeAppNotRunning, ErrAppNotRunning},
+	{CodeTimeout, ErrTimeout},
+	{CodeNotFound, ErrNotFound},
+	{CodeInvalidArgument, ErrInvalidArgument},
+}
+
+// Error is a classified error from an OmniFocus operation
+type Error struct {
+	Code      ErrorCode
+	Operation string
+	Message   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Operation, e.Message)
+}
+
+// Unwrap returns the sentinel error for the code, if any
+func (e *Error) Unwrap() error {
+	for _, s := range sentinels {
+		if s.code == e.Code {
+			return s.err
+		}
+	}
+	return nil
+}
+
+// TimeoutError is returned when a script does not finish within its timeout,
+// typically because OmniFocus is blocked by a modal dialog.
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Operation, e.Timeout)
+}
+
+// Is reports TimeoutError as ErrTimeout
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// CodeOf returns the error code classifying err, or CodeInternal when err
+// does not wrap a known sentinel
+func CodeOf(err error) ErrorCode {
+	var ofErr *Error
+	if errors.As(err, &ofErr) && ofErr.Code != "" {
+		return ofErr.Code
+	}
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return s.code
+		}
+	}
+	return CodeInternal
+}
+
+// scriptError is the error object a JXA script returns instead of its
+// normal result
+type scriptError struct {
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code"`
+}
+
+// newScriptError builds an Error from a script's error object. Scripts that
+// predate error codes are classified from the message.
+func newScriptError(operation, message string, code ErrorCode) *Error {
+	if code == "" {
+		code = CodeInternal
+		if strings.Contains(strings.ToLower(message), "not found") {
+			code = CodeNotFound
+		} else if strings.Contains(strings.ToLower(message), "required") {
+			code = CodeInvalidArgument
+		}
+	}
+	return &Error{Code: code, Operation: operation, Message: message}
+}
+
+// checkScriptError returns an Error if a list script's output is an error
+// object rather than the expected array
+func checkScriptError(operation string, output []byte) error {
+	trimmed := strings.TrimSpace(string(output))
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil
+	}
+	var se scriptError
+	if err := json.Unmarshal([]byte(trimmed), &se); err != nil || se.Error == "" {
+		return nil
+	}
+	return newScriptError(operation, se.Error, se.Code)
+}
+
+// osascriptErrors maps fragments of osascript's error output to codes. The
+// numbers are AppleEvent error codes.
+var osascriptErrors = []struct {
+	fragments []string
+	code      ErrorCode
+}{
+	{[]string{"(-1743)", "Not authorized to send Apple events", "Not authorised to send Apple events"}, CodePermissionDenied},
+	{[]string{"(-600)", "Application isn't running"}, CodeAppNotRunning},
+	{[]string{"(-1712)", "AppleEvent timed out"}, CodeTimeout},
+	{[]string{"(-1728)"}, CodeNotFound},
+	{[]string{"(-50)", "Parameter error"}, CodeInvalidArgument},
+}
+
+// classifyOsascriptError classifies osascript's combined output after a
+// failed run
+func classifyOsascriptError(output string) ErrorCode {
+	for _, entry := range osascriptErrors {
+		for _, fragment := range entry.fragments {
+			if strings.Contains(output, fragment) {
+				return entry.code
+			}
+		}
+	}
+	return CodeInternal
+}
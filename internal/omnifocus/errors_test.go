@@ -0,0 +1,78 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"typed error", &Error{Code: CodeNotFound, Operation: "complete_task", Message: "Task not found"}, CodeNotFound},
+		{"wrapped sentinel", fmt.Errorf("project x %w", ErrNotFound), CodeNotFound},
+		{"timeout error", &TimeoutError{Operation: "list_tasks", Timeout: time.Second}, CodeTimeout},
+		{"out of scope", fmt.Errorf("task t1 is %w", ErrOutOfScope), CodeOutOfScope},
+		{"read only", ErrReadOnly, CodeReadOnly},
+		{"joined sentinels", errors.Join(ErrNotFound, ErrTimeout, ErrOutOfScope), CodeOutOfScope},
+		{"unclassified", errors.New("boom"), CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyOsascriptError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   ErrorCode
+	}{
+		{"execution error: Not authorized to send Apple events to OmniFocus. (-1743)", CodePermissionDenied},
+		{"execution error: Error: Error: Application isn't running. (-600)", CodeAppNotRunning},
+		{"execution error: OmniFocus got an error: AppleEvent timed out. (-1712)", CodeTimeout},
+		{"execution error: Error: Can't get object. (-1728)", CodeNotFound},
+		{"execution error: Error: SyntaxError: Unexpected token (-2700)", CodeInternal},
+	}
+
+	for _, tt := range tests {
+		if got := classifyOsascriptError(tt.output); got != tt.want {
+			t.Errorf("classifyOsascriptError(%q) = %s, want %s", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestScriptErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	c := newNoCacheTestClient(func(script string, args ...string) ([]byte, error) {
+		switch script {
+		case "list_tasks.jxa":
+			return []byte(`{"error": "Project not found", "code": "NOT_FOUND"}`), nil
+		case "create_task.jxa":
+			return mustJSON(OperationResult{Error: "Task name required", Code: CodeInvalidArgument}), nil
+		case "complete_task.jxa":
+			// Scripts without a code are classified from the message
+			return mustJSON(OperationResult{Error: "Task not found"}), nil
+		}
+		return nil, errors.New("unexpected script")
+	})
+
+	if _, err := c.ListTasks(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListTasks: expected ErrNotFound, got %v", err)
+	}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("CreateTask: expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err := c.CompleteTask(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CompleteTask: expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package omnifocus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Freshness describes how current the data returned by reads was. Attach
+// one to a context with WithFreshness before calling the client; reads made
+// with that context record into it.
+type Freshness struct {
+	mu     sync.Mutex
+	cached bool
+	stale  bool
+	age    time.Duration
+}
+
+type freshnessKey struct{}
+
+// WithFreshness returns a context that records the freshness of reads made
+// with it
+func WithFreshness(ctx context.Context) (context.Context, *Freshness) {
+	f := &Freshness{}
+	return context.WithValue(ctx, freshnessKey{}, f), f
+}
+
+// recordFreshness notes a read served from the cache with data of the given
+// age. A request that makes several reads reports the oldest.
+func recordFreshness(ctx context.Context, age time.Duration, stale bool) {
+	f, ok := ctx.Value(freshnessKey{}).(*Freshness)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cached = true
+	f.stale = f.stale || stale
+	f.age = max(f.age, age)
+}
+
+// Stale reports whether any read was served stale data while it was being
+// refreshed
+func (f *Freshness) Stale() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stale
+}
+
+// Age returns the age of the oldest data served, or zero if every read went
+// to OmniFocus
+func (f *Freshness) Age() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.age
+}
+
+// Cached reports whether any read was served from the cache
+func (f *Freshness) Cached() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cached
+}
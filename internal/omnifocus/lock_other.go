@@ -0,0 +1,16 @@
+//go:build !unix
+
+package omnifocus
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable; the disk cache then relies
+// on atomic renames alone.
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile is a no-op where flock is unavailable
+func unlockFile(f *os.File) error {
+	return nil
+}
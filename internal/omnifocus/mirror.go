@@ -0,0 +1,388 @@
+package omnifocus
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	// Pure-Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// MirrorSchema is the schema of the SQLite mirror. Booleans are stored as
+// 0 or 1 and dates as ISO 8601 text. Tasks refer to tags by name, so
+// task_tags.tag_id is the first tag with that name, or NULL if none.
+const MirrorSchema = `CREATE TABLE folders (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	path TEXT NOT NULL -- enclosing folder names, outermost first, joined by " / "
+);
+CREATE TABLE projects (
+	id                        TEXT PRIMARY KEY,
+	name                      TEXT NOT NULL,
+	status                    TEXT NOT NULL,
+	note                      TEXT NOT NULL,
+	completed                 INTEGER NOT NULL,
+	number_of_tasks           INTEGER NOT NULL,
+	number_of_completed_tasks INTEGER NOT NULL,
+	folder_id                 TEXT REFERENCES folders(id)
+);
+CREATE TABLE tasks (
+	id                TEXT PRIMARY KEY,
+	name              TEXT NOT NULL,
+	note              TEXT NOT NULL,
+	completed         INTEGER NOT NULL,
+	flagged           INTEGER NOT NULL,
+	due_date          TEXT,
+	estimated_minutes INTEGER,
+	project_id        TEXT REFERENCES projects(id)
+);
+CREATE TABLE tags (
+	id        TEXT PRIMARY KEY,
+	name      TEXT NOT NULL,
+	available INTEGER NOT NULL
+);
+CREATE TABLE task_tags (
+	task_id  TEXT NOT NULL REFERENCES tasks(id),
+	tag_id   TEXT REFERENCES tags(id),
+	tag_name TEXT NOT NULL
+);
+CREATE INDEX tasks_project ON tasks(project_id);
+CREATE INDEX task_tags_task ON task_tags(task_id);
+CREATE INDEX task_tags_tag ON task_tags(tag_id);
+CREATE INDEX tags_name ON tags(name);`
+
+// mirrorTables lists the mirror's tables, children before parents
+var mirrorTables = []string{"task_tags", "tasks", "tags", "projects", "folders"}
+
+// MaxQueryRows bounds the number of rows a mirror query returns
+const MaxQueryRows = 1000
+
+// QueryResult holds the rows returned by a mirror query
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated is true if the query matched more than MaxQueryRows rows
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Mirror is a local SQLite copy of the OmniFocus database, filled from the
+// client's lists, so that questions spanning tasks, projects, tags and
+// folders can be answered with SQL in milliseconds. It is refreshed when
+// marked dirty, e.g. from Client.OnChange, and on a timer.
+type Mirror struct {
+	client OmniFocusClient
+	db     *sql.DB
+
+	// refreshMu serialises refreshes
+	refreshMu sync.Mutex
+	mu        sync.Mutex
+	// dirty is set when the data may be out of date; loaded once the
+	// first refresh succeeds
+	dirty  bool
+	loaded bool
+	// wake is signalled by MarkDirty to refresh in the background
+	wake chan struct{}
+}
+
+// NewMirror opens a mirror filled from client. The database is kept in
+// memory if path is empty, or in the file at path, which is recreated on
+// every start.
+func NewMirror(client OmniFocusClient, path string) (*Mirror, error) {
+	dsn := ":memory:"
+	if path != "" {
+		dsn = "file:" + path
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mirror: %w", err)
+	}
+	// A single connection keeps an in-memory database alive and lets
+	// queries be made read-only per connection
+	db.SetMaxOpenConns(1)
+
+	var drops strings.Builder
+	for _, table := range mirrorTables {
+		fmt.Fprintf(&drops, "DROP TABLE IF EXISTS %s;\n", table)
+	}
+	if _, err := db.Exec(drops.String() + MirrorSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create mirror schema: %w", err)
+	}
+
+	return &Mirror{
+		client: client,
+		db:     db,
+		dirty:  true,
+		wake:   make(chan struct{}, 1),
+	}, nil
+}
+
+// Close closes the mirror's database
+func (m *Mirror) Close() error {
+	return m.db.Close()
+}
+
+// MarkDirty records that the mirror may be out of date. The next query
+// refreshes it first, and a mirror started with Start refreshes in the
+// background. It never blocks, so it can be called from Client.OnChange.
+func (m *Mirror) MarkDirty() {
+	m.mu.Lock()
+	m.dirty = true
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start refreshes the mirror in the background when it is marked dirty and
+// at least every interval. The returned function stops it.
+func (m *Mirror) Start(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Mirror refresh failed: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-m.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// Refresh reloads every table from the client's lists, which are served
+// from its cache where possible. Queries see either the old or the new
+// contents, never a mix.
+func (m *Mirror) Refresh(ctx context.Context) error {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	// Changes from here on need another refresh
+	m.mu.Lock()
+	m.dirty = false
+	m.mu.Unlock()
+
+	err := m.load(ctx)
+	m.mu.Lock()
+	if err != nil {
+		m.dirty = true
+	} else {
+		m.loaded = true
+	}
+	m.mu.Unlock()
+	return err
+}
+
+// load replaces the mirror's contents in one transaction
+func (m *Mirror) load(ctx context.Context) error {
+	projects, err := m.client.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	tasks, err := m.client.ListTasks(ctx, "")
+	if err != nil {
+		return err
+	}
+	tags, err := m.client.ListTags(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range mirrorTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return err
+		}
+	}
+
+	folders := make(map[string]bool)
+	for _, p := range projects {
+		if p.FolderID == nil || folders[*p.FolderID] || len(p.FolderPath) == 0 {
+			continue
+		}
+		folders[*p.FolderID] = true
+		if _, err := tx.ExecContext(ctx, "INSERT INTO folders (id, name, path) VALUES (?, ?, ?)",
+			*p.FolderID, p.FolderPath[len(p.FolderPath)-1], strings.Join(p.FolderPath, " / ")); err != nil {
+			return err
+		}
+	}
+
+	insertProject, err := tx.PrepareContext(ctx, `INSERT INTO projects (id, name, status, note, completed,
+		number_of_tasks, number_of_completed_tasks, folder_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if _, err := insertProject.ExecContext(ctx, p.ID, p.Name, p.Status, p.Note, p.Completed,
+			p.NumberOfTasks, p.NumberOfCompletedTasks, p.FolderID); err != nil {
+			return err
+		}
+	}
+
+	tagIDs := make(map[string]string, len(tags))
+	insertTag, err := tx.PrepareContext(ctx, "INSERT INTO tags (id, name, available) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if _, ok := tagIDs[t.Name]; !ok {
+			tagIDs[t.Name] = t.ID
+		}
+		if _, err := insertTag.ExecContext(ctx, t.ID, t.Name, t.Available); err != nil {
+			return err
+		}
+	}
+
+	insertTask, err := tx.PrepareContext(ctx, `INSERT INTO tasks (id, name, note, completed, flagged,
+		due_date, estimated_minutes, project_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insertTaskTag, err := tx.PrepareContext(ctx, "INSERT INTO task_tags (task_id, tag_id, tag_name) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		if _, err := insertTask.ExecContext(ctx, t.ID, t.Name, t.Note, t.Completed, t.Flagged,
+			t.DueDate, t.EstimatedMinutes, t.ContainingProjectID); err != nil {
+			return err
+		}
+		for _, name := range t.Tags {
+			var tagID *string
+			if id, ok := tagIDs[name]; ok {
+				tagID = &id
+			}
+			if _, err := insertTaskTag.ExecContext(ctx, t.ID, tagID, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Query runs a read-only SQL query against the mirror, refreshing it first
+// if it is out of date. Only a single SELECT (or WITH) statement is
+// accepted, and at most MaxQueryRows rows are returned.
+func (m *Mirror) Query(ctx context.Context, query string, args ...any) (*QueryResult, error) {
+	query, err := checkReadOnlyQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	refresh := m.dirty || !m.loaded
+	m.mu.Unlock()
+	if refresh {
+		if err := m.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("failed to refresh mirror: %w", err)
+		}
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Let SQLite itself reject anything that would write
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidArgument)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		if len(result.Rows) == MaxQueryRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidArgument)
+	}
+	return result, nil
+}
+
+// checkReadOnlyQuery returns query without a trailing semicolon if it is a
+// single SELECT or WITH statement
+func checkReadOnlyQuery(query string) (string, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	first := strings.TrimLeftFunc(query, unicode.IsSpace)
+	if end := strings.IndexFunc(first, func(r rune) bool { return !unicode.IsLetter(r) }); end >= 0 {
+		first = first[:end]
+	}
+	switch strings.ToUpper(first) {
+	case "SELECT", "WITH":
+	default:
+		return "", fmt.Errorf("only SELECT queries are allowed: %w", ErrInvalidArgument)
+	}
+
+	// Reject further statements, ignoring semicolons inside quotes
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			return "", fmt.Errorf("only a single statement is allowed: %w", ErrInvalidArgument)
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,138 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func newTestMirror(t *testing.T, client OmniFocusClient) *Mirror {
+	t.Helper()
+	m, err := NewMirror(client, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func mirrorFixture() *fakeClient {
+	return &fakeClient{
+		projects: []Project{
+			{ID: "p1", Name: "Website", Status: "on hold", FolderID: strPtr("f1"), FolderPath: []string{"Work", "Clients"}},
+			{ID: "p2", Name: "Garden", Status: "active"},
+		},
+		tasks: []Task{
+			{ID: "t1", Name: "Chase invoice", Tags: []string{"Waiting"}, ContainingProjectID: strPtr("p1")},
+			{ID: "t2", Name: "Design review", Tags: []string{"Office"}, ContainingProjectID: strPtr("p1")},
+			{ID: "t3", Name: "Seeds", Tags: []string{"Waiting"}, ContainingProjectID: strPtr("p2")},
+			{ID: "t4", Name: "Inbox item", Tags: []string{"Unknown"}},
+		},
+		tags: []Tag{{ID: "g1", Name: "Waiting", Available: true}, {ID: "g2", Name: "Office", Available: true}},
+	}
+}
+
+func TestMirror_JoinsAcrossTables(t *testing.T) {
+	m := newTestMirror(t, mirrorFixture())
+
+	result, err := m.Query(context.Background(), `
+		SELECT t.name, f.path
+		FROM tasks t
+		JOIN projects p ON p.id = t.project_id
+		JOIN task_tags tt ON tt.task_id = t.id
+		JOIN tags g ON g.id = tt.tag_id
+		LEFT JOIN folders f ON f.id = p.folder_id
+		WHERE g.name = 'Waiting' AND p.status = 'on hold'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != "Chase invoice" || result.Rows[0][1] != "Work / Clients" {
+		t.Errorf("expected the waiting task in the on-hold project, got %+v", result)
+	}
+
+	// Tags the database does not know keep their name without an ID
+	result, err = m.Query(context.Background(), "SELECT tag_id, tag_name FROM task_tags WHERE task_id = ?", "t4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != nil || result.Rows[0][1] != "Unknown" {
+		t.Errorf("expected an unknown tag with a NULL id, got %+v", result.Rows)
+	}
+}
+
+func TestMirror_RejectsWrites(t *testing.T) {
+	m := newTestMirror(t, mirrorFixture())
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"DELETE FROM tasks",
+		"SELECT 1; DELETE FROM tasks",
+		"WITH x AS (SELECT 1) DELETE FROM tasks",
+		"SELECT * FROM missing",
+	} {
+		if _, err := m.Query(ctx, query); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%q: expected an invalid argument error, got %v", query, err)
+		}
+	}
+
+	result, err := m.Query(ctx, "select count(*) from tasks where name != ';';")
+	if err != nil || result.Rows[0][0] != int64(4) {
+		t.Errorf("expected the tasks to survive, got %+v (err=%v)", result, err)
+	}
+}
+
+func TestMirror_RefreshesWhenDirty(t *testing.T) {
+	f := mirrorFixture()
+	m := newTestMirror(t, f)
+	ctx := context.Background()
+	m.Query(ctx, "SELECT 1")
+
+	f.tasks = f.tasks[:1]
+	if result, _ := m.Query(ctx, "SELECT count(*) FROM tasks"); result.Rows[0][0] != int64(4) {
+		t.Errorf("expected the mirror to keep its data until marked dirty, got %v", result.Rows)
+	}
+	m.MarkDirty()
+	if result, _ := m.Query(ctx, "SELECT count(*) FROM tasks"); result.Rows[0][0] != int64(1) {
+		t.Errorf("expected the mirror to refresh once marked dirty, got %v", result.Rows)
+	}
+}
+
+func TestMirror_TruncatesLargeResults(t *testing.T) {
+	f := &fakeClient{}
+	for i := range MaxQueryRows + 5 {
+		f.tasks = append(f.tasks, Task{ID: fmt.Sprint(i)})
+	}
+	m := newTestMirror(t, f)
+
+	result, err := m.Query(context.Background(), "SELECT id FROM tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != MaxQueryRows || !result.Truncated {
+		t.Errorf("expected %d rows and truncation, got %d rows (truncated=%v)", MaxQueryRows, len(result.Rows), result.Truncated)
+	}
+}
+
+func TestClient_OnChange(t *testing.T) {
+	c := newTestClient(func(string, ...string) ([]byte, error) {
+		return mustJSON(OperationResult{
+			ID:      "t1",
+			Success: true,
+			Task:    &Task{ID: "t1", ContainingProjectID: strPtr("p1")},
+		}), nil
+	})
+	var changes []Change
+	c.OnChange(func(ch Change) { changes = append(changes, ch) })
+
+	c.CompleteTask(context.Background(), "t1")
+	c.ClearCache("")
+	want := []Change{
+		{TaskIDs: []string{"t1"}, ProjectIDs: []string{"p1"}},
+		{Tasks: true, Projects: true, Tags: true, ListChanged: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected changes %+v, got %+v", want, changes)
+	}
+}
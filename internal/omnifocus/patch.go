@@ -0,0 +1,80 @@
+package omnifocus
+
+import "strings"
+
+// The mutation scripts report the affected task and project as they are
+// after the change. Rather than dropping every cached list, which forces a
+// full re-read of the database on the next call, the client applies these
+// to the cached lists in place. Any list it cannot patch is dropped.
+
+// patchTaskLists applies a task mutation to the cached task and project
+// lists. created is true for a new task. projectAffected says whether the
+// project lists may have changed when the result does not say which project
+// the task is in.
+func (c *Client) patchTaskLists(result *OperationResult, created, projectAffected bool) {
+	if result.Task == nil {
+		c.taskCache.InvalidatePattern("tasks:")
+		if projectAffected {
+			c.projectCache.InvalidatePattern("projects:")
+		}
+		return
+	}
+
+	task := result.Task.Clone()
+	c.taskCache.Patch("tasks:", func(key string, tasks []Task) ([]Task, bool) {
+		belongs := key == "tasks:all"
+		if projectID, ok := strings.CutPrefix(key, "tasks:project:"); ok {
+			belongs = task.ContainingProjectID != nil && *task.ContainingProjectID == projectID
+		} else if !belongs {
+			return nil, false
+		}
+		return upsert(tasks, task, func(t Task) string { return t.ID }, belongs, created)
+	})
+
+	// Task counts only change on the task's own project
+	if task.ContainingProjectID != nil {
+		c.patchProjectLists(result.Project, false)
+	}
+}
+
+// patchProjectLists applies a changed project to the cached project lists,
+// or drops them if the project is not known. created is true for a new
+// project.
+func (c *Client) patchProjectLists(project *Project, created bool) {
+	if project == nil {
+		c.projectCache.InvalidatePattern("projects:")
+		return
+	}
+
+	p := project.Clone()
+	c.projectCache.Patch("projects:", func(key string, projects []Project) ([]Project, bool) {
+		if key != "projects:all" {
+			return nil, false
+		}
+		return upsert(projects, p, func(p Project) string { return p.ID }, true, created)
+	})
+}
+
+// upsert applies item to a cached list. If the item belongs in the list it
+// replaces the item with the same ID, or is appended if created; otherwise
+// it is removed from the list. upsert reports false if an existing item was
+// expected but not found, meaning the list is out of date.
+func upsert[T any](items []T, item T, id func(T) string, belongs, created bool) ([]T, bool) {
+	for i := range items {
+		if id(items[i]) != id(item) {
+			continue
+		}
+		if !belongs {
+			return append(items[:i], items[i+1:]...), true
+		}
+		items[i] = item
+		return items, true
+	}
+	switch {
+	case !belongs:
+		return items, true
+	case created:
+		return append(items, item), true
+	}
+	return nil, false
+}
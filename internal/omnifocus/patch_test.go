@@ -0,0 +1,138 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// mutationFixture serves cached lists and mutation results, counting list
+// calls so tests can tell a patched list from a reloaded one
+type mutationFixture struct {
+	tasks     []Task
+	result    OperationResult
+	taskCalls int
+	projCalls int
+}
+
+func (f *mutationFixture) run(script string, _ ...string) ([]byte, error) {
+	switch script {
+	case "list_tasks.jxa":
+		f.taskCalls++
+		return mustJSON(f.tasks), nil
+	case "list_projects.jxa":
+		f.projCalls++
+		return mustJSON([]Project{{ID: "p1", NumberOfTasks: 1}, {ID: "p2"}}), nil
+	case "create_task.jxa", "update_task.jxa", "complete_task.jxa", "create_project.jxa":
+		return mustJSON(f.result), nil
+	}
+	return nil, errors.New("unexpected script")
+}
+
+func TestCreateTask_PatchesCachedLists(t *testing.T) {
+	ctx := context.Background()
+	f := &mutationFixture{tasks: []Task{{ID: "t1", ContainingProjectID: strPtr("p1")}}}
+	c := newTestClient(f.run)
+	c.ListTasks(ctx, "")
+	c.ListTasks(ctx, "p1")
+	c.ListTasks(ctx, "p2")
+	c.ListProjects(ctx)
+
+	newTask := Task{ID: "t2", Name: "New", ContainingProjectID: strPtr("p1")}
+	f.result = OperationResult{ID: "t2", Success: true, Task: &newTask,
+		Project: &Project{ID: "p1", NumberOfTasks: 2}}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "New", ProjectID: "p1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ := c.ListTasks(ctx, "")
+	inProject, _ := c.ListTasks(ctx, "p1")
+	other, _ := c.ListTasks(ctx, "p2")
+	projects, _ := c.ListProjects(ctx)
+	if f.taskCalls != 3 || f.projCalls != 1 {
+		t.Fatalf("expected no reloads, got %d task and %d project fetches", f.taskCalls, f.projCalls)
+	}
+	if len(all) != 2 || all[1].ID != "t2" || len(inProject) != 2 {
+		t.Errorf("expected the task added to its lists, got all=%v project=%v", all, inProject)
+	}
+	if len(other) != 1 {
+		t.Errorf("expected other projects' lists unchanged, got %v", other)
+	}
+	if projects[0].NumberOfTasks != 2 || projects[1].ID != "p2" {
+		t.Errorf("expected the project's counts updated in place, got %+v", projects)
+	}
+}
+
+func TestCompleteTask_PatchesCachedTask(t *testing.T) {
+	ctx := context.Background()
+	f := &mutationFixture{tasks: []Task{{ID: "t1"}, {ID: "t2"}}}
+	c := newTestClient(f.run)
+	c.ListTasks(ctx, "")
+	c.ListProjects(ctx)
+
+	// An inbox task leaves the project list alone
+	f.result = OperationResult{ID: "t2", Success: true, Task: &Task{ID: "t2", Completed: true}}
+	if _, err := c.CompleteTask(ctx, "t2"); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, _ := c.ListTasks(ctx, "")
+	c.ListProjects(ctx)
+	if f.taskCalls != 1 || f.projCalls != 1 {
+		t.Fatalf("expected no reloads, got %d task and %d project fetches", f.taskCalls, f.projCalls)
+	}
+	if !tasks[1].Completed || tasks[0].Completed {
+		t.Errorf("expected only t2 completed, got %+v", tasks)
+	}
+}
+
+func TestUpdateTask_UnknownTaskDropsList(t *testing.T) {
+	ctx := context.Background()
+	f := &mutationFixture{tasks: []Task{{ID: "t1"}}}
+	c := newTestClient(f.run)
+	c.ListTasks(ctx, "")
+
+	// The cached list predates t9, so it cannot be patched
+	f.result = OperationResult{ID: "t9", Success: true, Task: &Task{ID: "t9", Flagged: true}}
+	if _, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t9"}); err != nil {
+		t.Fatal(err)
+	}
+	c.ListTasks(ctx, "")
+	if f.taskCalls != 2 {
+		t.Errorf("expected the list to be reloaded, got %d fetches", f.taskCalls)
+	}
+}
+
+func TestUpdateTask_ProjectNotReportedDropsProjects(t *testing.T) {
+	ctx := context.Background()
+	f := &mutationFixture{tasks: []Task{{ID: "t1", ContainingProjectID: strPtr("p1")}}}
+	c := newTestClient(f.run)
+	c.ListTasks(ctx, "")
+	c.ListProjects(ctx)
+
+	f.result = OperationResult{ID: "t1", Success: true, Task: &Task{ID: "t1", Name: "Renamed", ContainingProjectID: strPtr("p1")}}
+	if _, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	c.ListTasks(ctx, "")
+	c.ListProjects(ctx)
+	if f.taskCalls != 1 || f.projCalls != 2 {
+		t.Errorf("expected only projects reloaded, got %d task and %d project fetches", f.taskCalls, f.projCalls)
+	}
+}
+
+func TestCreateProject_PatchesCachedList(t *testing.T) {
+	ctx := context.Background()
+	f := &mutationFixture{}
+	c := newTestClient(f.run)
+	c.ListProjects(ctx)
+
+	f.result = OperationResult{ID: "p3", Success: true, Project: &Project{ID: "p3", Name: "New"}}
+	if _, err := c.CreateProject(ctx, CreateProjectRequest{Name: "New"}); err != nil {
+		t.Fatal(err)
+	}
+	projects, _ := c.ListProjects(ctx)
+	if f.projCalls != 1 || len(projects) != 3 || projects[2].ID != "p3" {
+		t.Errorf("expected the project appended without a reload, got %d fetches and %+v", f.projCalls, projects)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package omnifocus
+
+import (
+	"os/exec"
+	"time"
+)
+
+// configureProcessGroup bounds how long cmd may linger after cancellation.
+// Process groups are only used on Unix.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = time.Second
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package omnifocus
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureProcessGroup starts cmd in its own process group and kills the
+// whole group on cancellation, so helpers spawned by osascript cannot keep a
+// cancelled call alive.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package omnifocus
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestConfigureProcessGroup_KillsChildrenOnCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The shell forks a child that holds stdout open; without killing the
+	// whole group, CombinedOutput would wait for the child to exit.
+	cmd := exec.CommandContext(ctx, "sh", "-c", "sleep 30 & wait")
+	configureProcessGroup(cmd)
+
+	start := time.Now()
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Fatal("expected the command to be killed")
+	}
+	// WaitDelay alone would only release the call after a full second
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("command took %s to stop after cancellation", elapsed)
+	}
+}
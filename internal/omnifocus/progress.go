@@ -0,0 +1,64 @@
+package omnifocus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ProgressFunc receives updates from a long-running operation. progress
+// counts the updates, so it increases with each one; how many there will be
+// is not known in advance.
+type ProgressFunc func(progress int, message string)
+
+// progressReporter numbers the updates of one operation, so progress keeps
+// increasing across the several scripts, retries and phases it may run
+type progressReporter struct {
+	mu sync.Mutex
+	fn ProgressFunc
+	n  int
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose operations report their phases to
+// fn: waiting to retry, launching osascript, reading from OmniFocus and
+// parsing the result. A read that joins one already running for another
+// caller reports that read's remaining phases.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progressReporter{fn: fn})
+}
+
+// ReportProgress sends the next update to the context's ProgressFunc, if
+// it has one
+func ReportProgress(ctx context.Context, format string, args ...any) {
+	if p := progressFrom(ctx); p != nil {
+		p.report(fmt.Sprintf(format, args...))
+	}
+}
+
+// progressFrom returns the context's progress reporter, or nil
+func progressFrom(ctx context.Context) *progressReporter {
+	p, _ := ctx.Value(progressKey{}).(*progressReporter)
+	return p
+}
+
+// report sends the next update
+func (p *progressReporter) report(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.n++
+	p.fn(p.n, message)
+}
+
+// byteSize formats a script's output size for progress messages
+func byteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
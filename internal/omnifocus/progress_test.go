@@ -0,0 +1,69 @@
+package omnifocus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// recordProgress returns a context that collects progress messages
+func recordProgress(t *testing.T) (context.Context, *[]string) {
+	t.Helper()
+	var messages []string
+	ctx := WithProgress(context.Background(), func(progress int, message string) {
+		if progress != len(messages)+1 {
+			t.Errorf("expected progress %d, got %d", len(messages)+1, progress)
+		}
+		messages = append(messages, message)
+	})
+	return ctx, &messages
+}
+
+func TestProgress_ReportsPhases(t *testing.T) {
+	output := mustJSON([]Task{{ID: "t1"}})
+	c := newTestClient(func(string, ...string) ([]byte, error) {
+		return output, nil
+	})
+	ctx, messages := recordProgress(t)
+
+	if _, err := c.ListTasks(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Launching osascript for list_tasks", "Parsing tasks (" + byteSize(len(output)) + ")"}
+	if !reflect.DeepEqual(*messages, want) {
+		t.Errorf("expected %q, got %q", want, *messages)
+	}
+
+	// A cached read has nothing to report
+	*messages = nil
+	if _, err := c.ListTasks(ctx, ""); err != nil || len(*messages) != 0 {
+		t.Errorf("expected no progress from the cache, got %q, %v", *messages, err)
+	}
+}
+
+func TestProgress_ReportsRetries(t *testing.T) {
+	exec := &flakyExecutor{failures: 1, err: errAppNotRunning, output: mustJSON([]Tag{{ID: "g1"}})}
+	c := newRetryTestClient(exec, 2)
+	ctx, messages := recordProgress(t)
+
+	if _, err := c.ListTags(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Launching osascript for list_tags",
+		"Retrying list_tags in 1ms after a transient error",
+		"Launching osascript for list_tags",
+		"Parsing tags (" + byteSize(len(exec.output)) + ")",
+	}
+	if !reflect.DeepEqual(*messages, want) {
+		t.Errorf("expected %q, got %q", want, *messages)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for n, want := range map[int]string{512: "512 bytes", 2048: "2.0 KB", 3 << 20: "3.0 MB"} {
+		if got := byteSize(n); got != want {
+			t.Errorf("byteSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly is returned by mutations when the server runs in read-only mode
+var ErrReadOnly = errors.New("server is in read-only mode")
+
+// ReadOnlyClient wraps an OmniFocusClient and rejects every mutation with
+// ErrReadOnly while passing reads through unchanged.
+type ReadOnlyClient struct {
+	OmniFocusClient
+}
+
+// NewReadOnlyClient creates a client that only allows reads from inner
+func NewReadOnlyClient(inner OmniFocusClient) *ReadOnlyClient {
+	return &ReadOnlyClient{OmniFocusClient: inner}
+}
+
+// CreateTask always fails with ErrReadOnly
+func (c *ReadOnlyClient) CreateTask(ctx context.Context, req CreateTaskRequest) (*OperationResult, error) {
+	return nil, ErrReadOnly
+}
+
+// CreateProject always fails with ErrReadOnly
+func (c *ReadOnlyClient) CreateProject(ctx context.Context, req CreateProjectRequest) (*OperationResult, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateTask always fails with ErrReadOnly
+func (c *ReadOnlyClient) UpdateTask(ctx context.Context, req UpdateTaskRequest) (*OperationResult, error) {
+	return nil, ErrReadOnly
+}
+
+// CompleteTask always fails with ErrReadOnly
+func (c *ReadOnlyClient) CompleteTask(ctx context.Context, taskID string) (*OperationResult, error) {
+	return nil, ErrReadOnly
+}
@@ -0,0 +1,39 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReadOnlyClient_RejectsMutations(t *testing.T) {
+	ctx := context.Background()
+	inner := newScopeFixture()
+	c := NewReadOnlyClient(inner)
+
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "x"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateTask: expected ErrReadOnly, got %v", err)
+	}
+	if _, err := c.CreateProject(ctx, CreateProjectRequest{Name: "x"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateProject: expected ErrReadOnly, got %v", err)
+	}
+	if _, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t1"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("UpdateTask: expected ErrReadOnly, got %v", err)
+	}
+	if _, err := c.CompleteTask(ctx, "t1"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CompleteTask: expected ErrReadOnly, got %v", err)
+	}
+	if len(inner.created) != 0 || len(inner.updated) != 0 || len(inner.completed) != 0 {
+		t.Error("mutations must not reach the inner client")
+	}
+}
+
+func TestReadOnlyClient_PassesReadsThrough(t *testing.T) {
+	ctx := context.Background()
+	c := NewReadOnlyClient(newScopeFixture())
+
+	tasks, err := c.ListTasks(ctx, "")
+	if err != nil || len(tasks) != 5 {
+		t.Errorf("err=%v len=%d", err, len(tasks))
+	}
+}
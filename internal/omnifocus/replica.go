@@ -0,0 +1,202 @@
+package omnifocus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sinceFormat is how the start of the last sync is passed to
+// list_tasks.jxa. JavaScript dates have millisecond precision.
+const sinceFormat = "2006-01-02T15:04:05.000Z"
+
+// taskDelta is the output of list_tasks.jxa with --since: the tasks modified
+// since then and the IDs of every task, in database order
+type taskDelta struct {
+	Tasks []Task   `json:"tasks"`
+	IDs   []string `json:"ids"`
+}
+
+// taskReplica is a local copy of every task in the database. After a full
+// read it is kept current by fetching only the tasks modified since the
+// previous sync, and comparing ID sets to find deleted tasks.
+type taskReplica struct {
+	mu    sync.Mutex
+	tasks map[string]Task
+	// order holds the task IDs in database order
+	order []string
+	// syncedAt is when the last sync started, so changes made while it
+	// ran are fetched again by the next one
+	syncedAt time.Time
+	// fullAt is when the last full read started
+	fullAt time.Time
+	// fullSyncInterval bounds the time between full reads; zero means
+	// only read everything when the replica is empty
+	fullSyncInterval time.Duration
+}
+
+// since returns the time to fetch changes from, or false if the next sync
+// must read every task
+func (r *taskReplica) since(now time.Time) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tasks == nil || (r.fullSyncInterval > 0 && now.Sub(r.fullAt) >= r.fullSyncInterval) {
+		return time.Time{}, false
+	}
+	return r.syncedAt, true
+}
+
+// replace loads the result of a full read started at start
+func (r *taskReplica) replace(start time.Time, tasks []Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if start.Before(r.syncedAt) {
+		// A sync that started later has already been applied
+		return
+	}
+	r.tasks = make(map[string]Task, len(tasks))
+	r.order = make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		r.tasks[t.ID] = t.Clone()
+		r.order = append(r.order, t.ID)
+	}
+	r.syncedAt, r.fullAt = start, start
+}
+
+// merge applies a delta fetched from start. It reports false if the delta
+// lists a task the replica has never seen and that was not reported as
+// modified, e.g. one synced from another device with an older
+// modification date, in which case the replica needs a full read.
+func (r *taskReplica) merge(start time.Time, delta taskDelta) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tasks == nil {
+		return false
+	}
+	if start.Before(r.syncedAt) {
+		return true
+	}
+
+	tasks := make(map[string]Task, len(delta.IDs))
+	for _, t := range delta.Tasks {
+		tasks[t.ID] = t.Clone()
+	}
+	for _, id := range delta.IDs {
+		if _, ok := tasks[id]; ok {
+			continue
+		}
+		t, ok := r.tasks[id]
+		if !ok {
+			return false
+		}
+		tasks[id] = t
+	}
+
+	// Tasks missing from the ID list were deleted
+	r.tasks = tasks
+	r.order = delta.IDs
+	r.syncedAt = start
+	return true
+}
+
+// list returns a copy of the tasks, in database order, limited to those in
+// projectID if it is not empty
+func (r *taskReplica) list(projectID string) []Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tasks := make([]Task, 0, len(r.order))
+	for _, id := range r.order {
+		t := r.tasks[id]
+		if projectID != "" && (t.ContainingProjectID == nil || *t.ContainingProjectID != projectID || t.ID == projectID) {
+			// A project's own root task is not one of its tasks
+			continue
+		}
+		tasks = append(tasks, t.Clone())
+	}
+	return tasks
+}
+
+// reset empties the replica so the next sync reads every task. Syncs
+// already running are discarded.
+func (r *taskReplica) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tasks = nil
+	r.order = nil
+	r.syncedAt = time.Now()
+}
+
+// SetIncrementalSync makes task lists come from a local replica of the
+// database. Rather than reading every task on each cache miss, the client
+// then fetches only the tasks modified since its last read. Edits can reach
+// OmniFocus with older modification dates, e.g. when synced from another
+// device, so the replica is read in full at least every fullSyncInterval;
+// zero never forces a full read.
+func (c *Client) SetIncrementalSync(fullSyncInterval time.Duration) {
+	c.replica = &taskReplica{fullSyncInterval: fullSyncInterval}
+}
+
+// resetReplica empties the incremental sync replica, if there is one
+func (c *Client) resetReplica() {
+	if c.replica != nil {
+		c.replica.reset()
+	}
+}
+
+// syncTasks brings the replica up to date and returns the tasks in
+// projectID, or every task if projectID is empty
+func (c *Client) syncTasks(ctx context.Context, projectID string) ([]Task, error) {
+	r := c.replica
+	start := time.Now()
+	if since, ok := r.since(start); ok {
+		output, err := c.executeJXA(ctx, "list_tasks.jxa", "--since="+since.UTC().Format(sinceFormat))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkScriptError("list_tasks", output); err != nil {
+			return nil, err
+		}
+		ReportProgress(ctx, "Parsing task changes (%s)", byteSize(len(output)))
+		var delta taskDelta
+		if err := json.Unmarshal(output, &delta); err != nil {
+			return nil, fmt.Errorf("failed to parse task changes: %w", err)
+		}
+		if r.merge(start, delta) {
+			return c.replicaTasks(ctx, projectID)
+		}
+	}
+
+	tasks, err := c.fetchTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.replace(start, tasks)
+	return c.replicaTasks(ctx, projectID)
+}
+
+// replicaTasks lists the replica's tasks in projectID. Like list_tasks.jxa,
+// it fails with CodeNotFound for a project that does not exist, which only
+// an empty list needs to be checked for.
+func (c *Client) replicaTasks(ctx context.Context, projectID string) ([]Task, error) {
+	tasks := c.replica.list(projectID)
+	if projectID == "" || len(tasks) > 0 {
+		return tasks, nil
+	}
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.ID == projectID {
+			return tasks, nil
+		}
+	}
+	return nil, newScriptError("list_tasks", "Project not found", CodeNotFound)
+}
@@ -0,0 +1,149 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syncFixture serves list_tasks.jxa in full and --since modes from a
+// database that tests can edit between calls
+type syncFixture struct {
+	projects  []Project
+	tasks     []Task
+	modified  []Task
+	fullReads int
+	since     []string
+}
+
+func (f *syncFixture) run(script string, args ...string) ([]byte, error) {
+	if script == "list_projects.jxa" {
+		return mustJSON(f.projects), nil
+	}
+	for _, arg := range args {
+		if since, ok := strings.CutPrefix(arg, "--since="); ok {
+			f.since = append(f.since, since)
+			ids := make([]string, len(f.tasks))
+			for i, t := range f.tasks {
+				ids[i] = t.ID
+			}
+			return mustJSON(taskDelta{Tasks: f.modified, IDs: ids}), nil
+		}
+	}
+	f.fullReads++
+	return mustJSON(f.tasks), nil
+}
+
+func newSyncClient(f *syncFixture, fullSyncInterval time.Duration) *Client {
+	c := newNoCacheTestClient(f.run)
+	c.SetIncrementalSync(fullSyncInterval)
+	return c
+}
+
+func TestIncrementalSync_MergesChangesAndDeletions(t *testing.T) {
+	ctx := context.Background()
+	f := &syncFixture{tasks: []Task{{ID: "t1", Name: "One"}, {ID: "t2"}, {ID: "t3"}}}
+	c := newSyncClient(f, 0)
+	c.ListTasks(ctx, "")
+
+	// t1 renamed, t2 deleted and t4 added
+	f.modified = []Task{{ID: "t1", Name: "Renamed"}, {ID: "t4"}}
+	f.tasks = []Task{f.modified[0], {ID: "t3"}, f.modified[1]}
+	tasks, err := c.ListTasks(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.fullReads != 1 || len(f.since) != 1 {
+		t.Fatalf("expected one full read then one delta, got %d and %d", f.fullReads, len(f.since))
+	}
+	if _, err := time.Parse(time.RFC3339, f.since[0]); err != nil {
+		t.Errorf("expected an ISO date for --since, got %q", f.since[0])
+	}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	if strings.Join(ids, ",") != "t1,t3,t4" || tasks[0].Name != "Renamed" {
+		t.Errorf("expected t1,t3,t4 with t1 renamed, got %+v", tasks)
+	}
+}
+
+func TestIncrementalSync_UnknownTaskForcesFullRead(t *testing.T) {
+	ctx := context.Background()
+	f := &syncFixture{tasks: []Task{{ID: "t1"}}}
+	c := newSyncClient(f, 0)
+	c.ListTasks(ctx, "")
+
+	// t2 arrived with a modification date older than the last sync
+	f.tasks = append(f.tasks, Task{ID: "t2"})
+	tasks, _ := c.ListTasks(ctx, "")
+	if f.fullReads != 2 || len(tasks) != 2 {
+		t.Errorf("expected a second full read returning both tasks, got %d reads and %+v", f.fullReads, tasks)
+	}
+}
+
+func TestIncrementalSync_FiltersByProject(t *testing.T) {
+	ctx := context.Background()
+	f := &syncFixture{tasks: []Task{
+		{ID: "p1", ContainingProjectID: strPtr("p1")},
+		{ID: "t1", ContainingProjectID: strPtr("p1")},
+		{ID: "t2", ContainingProjectID: strPtr("p2")},
+		{ID: "t3"},
+	}}
+	c := newSyncClient(f, 0)
+
+	tasks, err := c.ListTasks(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "t1" {
+		t.Errorf("expected only t1, got %+v", tasks)
+	}
+}
+
+func TestIncrementalSync_UnknownProject(t *testing.T) {
+	ctx := context.Background()
+	f := &syncFixture{
+		projects: []Project{{ID: "p1"}, {ID: "p-empty"}},
+		tasks:    []Task{{ID: "t1", ContainingProjectID: strPtr("p1")}},
+	}
+	c := newSyncClient(f, 0)
+
+	if tasks, err := c.ListTasks(ctx, "p-empty"); err != nil || len(tasks) != 0 {
+		t.Errorf("expected an empty project to have no tasks, got %v, %v", tasks, err)
+	}
+	_, err := c.ListTasks(ctx, "p-missing")
+	if !errors.Is(err, ErrNotFound) || CodeOf(err) != CodeNotFound {
+		t.Errorf("expected an unknown project to be not found, got %v", err)
+	}
+}
+
+func TestIncrementalSync_FullReadInterval(t *testing.T) {
+	ctx := context.Background()
+	f := &syncFixture{tasks: []Task{{ID: "t1"}}}
+	c := newSyncClient(f, 20*time.Millisecond)
+
+	c.ListTasks(ctx, "")
+	c.ListTasks(ctx, "")
+	time.Sleep(30 * time.Millisecond)
+	c.ListTasks(ctx, "")
+	if f.fullReads != 2 || len(f.since) != 1 {
+		t.Errorf("expected full, delta, full; got %d full reads and %d deltas", f.fullReads, len(f.since))
+	}
+}
+
+func TestIncrementalSync_ClearCacheResetsReplica(t *testing.T) {
+	ctx := context.Background()
+	f := &syncFixture{tasks: []Task{{ID: "t1"}}}
+	c := newSyncClient(f, 0)
+
+	c.ListTasks(ctx, "")
+	c.ClearCache("tasks:")
+	c.ListTasks(ctx, "")
+	if f.fullReads != 2 {
+		t.Errorf("expected clearing the cache to force a full read, got %d", f.fullReads)
+	}
+}
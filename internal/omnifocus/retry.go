@@ -0,0 +1,91 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how script executions that fail with a transient
+// error are retried. Only reads and idempotent writes are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Each further
+	// retry doubles it, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter randomises each delay by up to this fraction, so parallel
+	// callers do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the retry policy used by new clients
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+// idempotentOperations lists the operations that may safely run more than
+// once. Creating tasks or projects is excluded because a retry after a lost
+// reply would create a duplicate, and completing a task because completing
+// a repeating task moves the same ID on to its next occurrence, which a
+// retry would complete too. UpdateTask skips retries when it completes a
+// task for the same reason.
+var idempotentOperations = map[string]bool{
+	"list_projects":  true,
+	"list_tasks":     true,
+	"list_tags":      true,
+	"database_state": true,
+	"update_task":    true,
+}
+
+// IsTransient reports whether err is worth retrying: AppleEvent timeouts
+// and OmniFocus not running, both of which happen while OmniFocus syncs or
+// restarts. Timeouts enforced by the client itself are not transient, since
+// a retry would only wait as long again.
+func IsTransient(err error) bool {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return false
+	}
+	switch CodeOf(err) {
+	case CodeTimeout, CodeAppNotRunning:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, starting at 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,170 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyExecutor fails with err for the first failures calls, then returns
+// output. It records how many times it was called.
+type flakyExecutor struct {
+	failures int
+	err      error
+	output   []byte
+	calls    int
+}
+
+func (f *flakyExecutor) run(_ context.Context, _ string, _ ...string) ([]byte, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return f.output, nil
+}
+
+// newRetryTestClient creates an uncached client with fast retries.
+func newRetryTestClient(exec *flakyExecutor, attempts int) *Client {
+	c := NewClientWithCache("/fake/scripts", 0)
+	c.executor = exec.run
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond})
+	return c
+}
+
+var errAppNotRunning = &Error{Code: CodeAppNotRunning, Operation: "test", Message: "Application isn't running. (-600)"}
+
+func TestRetry_ReadRecoversFromTransientErrors(t *testing.T) {
+	exec := &flakyExecutor{failures: 2, err: errAppNotRunning, output: mustJSON([]Task{{ID: "t1"}})}
+	c := newRetryTestClient(exec, 3)
+
+	tasks, err := c.ListTasks(context.Background(), "")
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("err=%v tasks=%v", err, tasks)
+	}
+	if exec.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", exec.calls)
+	}
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	exec := &flakyExecutor{failures: 5, err: &Error{Code: CodeTimeout, Message: "AppleEvent timed out. (-1712)"}}
+	c := newRetryTestClient(exec, 3)
+
+	_, err := c.ListProjects(context.Background())
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if exec.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", exec.calls)
+	}
+}
+
+func TestRetry_IdempotentWriteIsRetried(t *testing.T) {
+	exec := &flakyExecutor{failures: 1, err: errAppNotRunning, output: mustJSON(OperationResult{ID: "t1", Success: true})}
+	c := newRetryTestClient(exec, 3)
+
+	flagged := true
+	if _, err := c.UpdateTask(context.Background(), UpdateTaskRequest{ID: "t1", Flagged: &flagged}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exec.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", exec.calls)
+	}
+}
+
+func TestRetry_CompletingIsNotRetried(t *testing.T) {
+	// Completing a repeating task again would complete its next occurrence
+	completed := true
+	for name, complete := range map[string]func(*Client) error{
+		"CompleteTask": func(c *Client) error {
+			_, err := c.CompleteTask(context.Background(), "t1")
+			return err
+		},
+		"UpdateTask": func(c *Client) error {
+			_, err := c.UpdateTask(context.Background(), UpdateTaskRequest{ID: "t1", Completed: &completed})
+			return err
+		},
+	} {
+		exec := &flakyExecutor{failures: 1, err: errAppNotRunning, output: mustJSON(OperationResult{ID: "t1", Success: true})}
+		c := newRetryTestClient(exec, 3)
+		if err := complete(c); !errors.Is(err, ErrAppNotRunning) {
+			t.Errorf("%s: expected ErrAppNotRunning, got %v", name, err)
+		}
+		if exec.calls != 1 {
+			t.Errorf("%s: expected 1 call, got %d", name, exec.calls)
+		}
+	}
+}
+
+func TestRetry_CreateIsNotRetried(t *testing.T) {
+	exec := &flakyExecutor{failures: 1, err: errAppNotRunning, output: mustJSON(OperationResult{ID: "t1", Success: true})}
+	c := newRetryTestClient(exec, 3)
+
+	if _, err := c.CreateTask(context.Background(), CreateTaskRequest{Name: "x"}); !errors.Is(err, ErrAppNotRunning) {
+		t.Fatalf("expected ErrAppNotRunning, got %v", err)
+	}
+	if exec.calls != 1 {
+		t.Errorf("expected 1 call, got %d", exec.calls)
+	}
+}
+
+func TestRetry_PermanentErrorIsNotRetried(t *testing.T) {
+	exec := &flakyExecutor{failures: 1, err: &Error{Code: CodePermissionDenied, Message: "(-1743)"}}
+	c := newRetryTestClient(exec, 3)
+
+	if _, err := c.ListTags(context.Background()); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	if exec.calls != 1 {
+		t.Errorf("expected 1 call, got %d", exec.calls)
+	}
+}
+
+func TestRetry_CancelledDuringBackoff(t *testing.T) {
+	exec := &flakyExecutor{failures: 5, err: errAppNotRunning}
+	c := NewClientWithCache("/fake/scripts", 0)
+	c.executor = exec.run
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := c.ListTasks(ctx, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context error, got %v", err)
+	}
+	if exec.calls != 1 {
+		t.Errorf("expected 1 call before cancellation, got %d", exec.calls)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("jittered backoff %s outside [50ms, 150ms]", got)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	if !IsTransient(errAppNotRunning) {
+		t.Error("app not running should be transient")
+	}
+	if IsTransient(&TimeoutError{Operation: "list_tasks", Timeout: time.Second}) {
+		t.Error("client-side timeouts should not be transient")
+	}
+	if IsTransient(errors.New("boom")) {
+		t.Error("unclassified errors should not be transient")
+	}
+}
@@ -0,0 +1,147 @@
+package omnifocus
+
+import (
+	"context"
+	"sync"
+)
+
+// Default concurrency limits for script executions
+const (
+	DefaultMaxConcurrentReads  = 2
+	DefaultMaxConcurrentWrites = 1
+)
+
+// Scheduler bounds how many scripts run against OmniFocus at once. Requests
+// are admitted strictly in arrival order: reads may run alongside other
+// reads, writes only alongside other writes. A read queued behind a write
+// therefore never starts before that write has finished, and a write waits
+// for the reads queued before it.
+type Scheduler struct {
+	mu           sync.Mutex
+	maxReads     int
+	maxWrites    int
+	activeReads  int
+	activeWrites int
+	queue        []*schedulerWaiter
+}
+
+// schedulerWaiter is a queued request; ready is closed once it is admitted
+type schedulerWaiter struct {
+	write bool
+	ready chan struct{}
+}
+
+// SchedulerStats is a snapshot of the scheduler's state
+type SchedulerStats struct {
+	Queued       int `json:"queued"`
+	ActiveReads  int `json:"activeReads"`
+	ActiveWrites int `json:"activeWrites"`
+}
+
+// NewScheduler creates a scheduler allowing up to maxReads concurrent reads
+// and maxWrites concurrent writes. Limits below 1 are treated as 1.
+func NewScheduler(maxReads, maxWrites int) *Scheduler {
+	return &Scheduler{
+		maxReads:  max(maxReads, 1),
+		maxWrites: max(maxWrites, 1),
+	}
+}
+
+// Acquire waits until the request may run and returns a function that must
+// be called when it finishes. If ctx is done first, the request leaves the
+// queue and ctx's error is returned.
+func (s *Scheduler) Acquire(ctx context.Context, write bool) (release func(), err error) {
+	w := &schedulerWaiter{write: write, ready: make(chan struct{})}
+
+	s.mu.Lock()
+	s.queue = append(s.queue, w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(write), nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// Admitted while cancelling; give the slot back
+		s.finish(write)
+	default:
+		for i, queued := range s.queue {
+			if queued == w {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+		// The head of the queue may be admissible now
+		s.dispatch()
+	}
+	return nil, ctx.Err()
+}
+
+// releaseFunc returns an idempotent release function for an admitted request
+func (s *Scheduler) releaseFunc(write bool) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.finish(write)
+		})
+	}
+}
+
+// finish marks a request as done and admits waiting requests. The caller
+// must hold s.mu.
+func (s *Scheduler) finish(write bool) {
+	if write {
+		s.activeWrites--
+	} else {
+		s.activeReads--
+	}
+	s.dispatch()
+}
+
+// dispatch admits requests from the head of the queue for as long as they
+// fit. The caller must hold s.mu.
+func (s *Scheduler) dispatch() {
+	for len(s.queue) > 0 {
+		head := s.queue[0]
+		if head.write {
+			if s.activeReads > 0 || s.activeWrites >= s.maxWrites {
+				return
+			}
+			s.activeWrites++
+		} else {
+			if s.activeWrites > 0 || s.activeReads >= s.maxReads {
+				return
+			}
+			s.activeReads++
+		}
+		s.queue = s.queue[1:]
+		close(head.ready)
+	}
+}
+
+// QueueDepth returns the number of requests waiting to run
+func (s *Scheduler) QueueDepth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Stats returns a snapshot of queued and running requests
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStats{
+		Queued:       len(s.queue),
+		ActiveReads:  s.activeReads,
+		ActiveWrites: s.activeWrites,
+	}
+}
@@ -0,0 +1,159 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler_BoundsConcurrentReads(t *testing.T) {
+	s := NewScheduler(2, 1)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), false)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() != 2 {
+		t.Errorf("expected peak of 2 concurrent reads, got %d", peak.Load())
+	}
+}
+
+func TestScheduler_ReadAfterWriteWaitsForWrite(t *testing.T) {
+	s := NewScheduler(4, 1)
+	ctx := context.Background()
+
+	// A read is running when the write arrives
+	releaseRead1, _ := s.Acquire(ctx, false)
+
+	var order []string
+	var mu sync.Mutex
+	record := func(event string) {
+		mu.Lock()
+		order = append(order, event)
+		mu.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		release, _ := s.Acquire(ctx, true)
+		record("write start")
+		time.Sleep(10 * time.Millisecond)
+		record("write end")
+		release()
+		done <- struct{}{}
+	}()
+	waitFor(t, "write to queue", func() bool { return s.QueueDepth() == 1 })
+
+	go func() {
+		release, _ := s.Acquire(ctx, false)
+		record("read2 start")
+		release()
+		done <- struct{}{}
+	}()
+	waitFor(t, "read to queue behind write", func() bool { return s.QueueDepth() == 2 })
+
+	if stats := s.Stats(); stats.ActiveReads != 1 || stats.Queued != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	releaseRead1()
+	<-done
+	<-done
+
+	want := []string{"write start", "write end", "read2 start"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestScheduler_CancelWhileQueued(t *testing.T) {
+	s := NewScheduler(1, 1)
+	release, _ := s.Acquire(context.Background(), true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, false)
+		errc <- err
+	}()
+	waitFor(t, "read to queue", func() bool { return s.QueueDepth() == 1 })
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if s.QueueDepth() != 0 {
+		t.Errorf("cancelled request should leave the queue, depth=%d", s.QueueDepth())
+	}
+
+	release()
+	if _, err := s.Acquire(context.Background(), false); err != nil {
+		t.Errorf("scheduler should accept new requests: %v", err)
+	}
+}
+
+func TestClient_WritesAreSerialized(t *testing.T) {
+	var running, peak atomic.Int32
+	c := NewClientWithCache("/fake/scripts", 0)
+	c.executor = func(context.Context, string, ...string) ([]byte, error) {
+		n := running.Add(1)
+		if n > peak.Load() {
+			peak.Store(n)
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return mustJSON(OperationResult{ID: "t1", Success: true}), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.CompleteTask(context.Background(), "t1")
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() != 1 {
+		t.Errorf("expected writes to run one at a time, peak=%d", peak.Load())
+	}
+}
@@ -0,0 +1,357 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrOutOfScope is returned when an operation targets an item outside the
+// configured scope.
+var ErrOutOfScope = errors.New("outside the configured scope")
+
+// Scope restricts which folders, projects and tags are visible to and
+// modifiable by the assistant. Entries match either the ID or the name
+// (case-insensitive) of an item. Tasks carry only tag names, so
+// ScopedClient resolves tag IDs to names before checking tasks.
+//
+// Deny lists always win. When any allow list is non-empty, an item must
+// match at least one of them: a project is allowed by its own entry or by an
+// enclosing folder, a task by its project or by one of its tags. Projects
+// carry no tags, so an allow list of tags alone leaves every project visible
+// and restricts only their tasks.
+type Scope struct {
+	AllowFolders  []string `json:"allowFolders,omitempty" yaml:"allowFolders,omitempty"`
+	AllowProjects []string `json:"allowProjects,omitempty" yaml:"allowProjects,omitempty"`
+	AllowTags     []string `json:"allowTags,omitempty" yaml:"allowTags,omitempty"`
+	DenyFolders   []string `json:"denyFolders,omitempty" yaml:"denyFolders,omitempty"`
+	DenyProjects  []string `json:"denyProjects,omitempty" yaml:"denyProjects,omitempty"`
+	DenyTags      []string `json:"denyTags,omitempty" yaml:"denyTags,omitempty"`
+}
+
+// IsEmpty reports whether the scope places no restrictions at all
+func (s Scope) IsEmpty() bool {
+	return len(s.AllowFolders) == 0 && len(s.AllowProjects) == 0 && len(s.AllowTags) == 0 &&
+		len(s.DenyFolders) == 0 && len(s.DenyProjects) == 0 && len(s.DenyTags) == 0
+}
+
+// hasAllowList reports whether any allow list is configured
+func (s Scope) hasAllowList() bool {
+	return len(s.AllowFolders) > 0 || len(s.AllowProjects) > 0 || len(s.AllowTags) > 0
+}
+
+// matchesAny reports whether any of the candidates matches an entry in list
+func matchesAny(list []string, candidates ...string) bool {
+	for _, entry := range list {
+		for _, c := range candidates {
+			if c != "" && strings.EqualFold(entry, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// projectFolders returns the folder ID and folder names enclosing a project
+func projectFolders(p Project) []string {
+	folders := append([]string{}, p.FolderPath...)
+	if p.FolderID != nil {
+		folders = append(folders, *p.FolderID)
+	}
+	return folders
+}
+
+// projectDenied reports whether a project is excluded by a deny list
+func (s Scope) projectDenied(p Project) bool {
+	return matchesAny(s.DenyProjects, p.ID, p.Name) || matchesAny(s.DenyFolders, projectFolders(p)...)
+}
+
+// projectAllowed reports whether a project is explicitly allowed by the
+// folder or project allow lists
+func (s Scope) projectAllowed(p Project) bool {
+	return matchesAny(s.AllowProjects, p.ID, p.Name) || matchesAny(s.AllowFolders, projectFolders(p)...)
+}
+
+// ProjectInScope reports whether a project is visible under the scope
+func (s Scope) ProjectInScope(p Project) bool {
+	if s.projectDenied(p) {
+		return false
+	}
+	if !s.hasAllowList() {
+		return true
+	}
+	// Projects carry no tags, so a tag-only allow list leaves them visible
+	// and restricts their tasks instead
+	if len(s.AllowFolders) == 0 && len(s.AllowProjects) == 0 {
+		return true
+	}
+	return s.projectAllowed(p)
+}
+
+// TaskInScope reports whether a task is visible under the scope. The project
+// is nil for inbox tasks.
+func (s Scope) TaskInScope(t Task, project *Project) bool {
+	if matchesAny(s.DenyTags, t.Tags...) {
+		return false
+	}
+	if project != nil && s.projectDenied(*project) {
+		return false
+	}
+	if !s.hasAllowList() {
+		return true
+	}
+	if project != nil && s.projectAllowed(*project) {
+		return true
+	}
+	return matchesAny(s.AllowTags, t.Tags...)
+}
+
+// TagInScope reports whether a tag is visible under the scope
+func (s Scope) TagInScope(tag Tag) bool {
+	if matchesAny(s.DenyTags, tag.ID, tag.Name) {
+		return false
+	}
+	if len(s.AllowTags) == 0 {
+		return true
+	}
+	return matchesAny(s.AllowTags, tag.ID, tag.Name)
+}
+
+// ScopedClient wraps an OmniFocusClient and enforces a Scope on every call:
+// list results are filtered and mutations targeting out-of-scope items are
+// rejected with ErrOutOfScope.
+type ScopedClient struct {
+	inner OmniFocusClient
+	scope Scope
+}
+
+// NewScopedClient creates a client that restricts inner to the given scope
+func NewScopedClient(inner OmniFocusClient, scope Scope) *ScopedClient {
+	return &ScopedClient{inner: inner, scope: scope}
+}
+
+// projectsByID returns all projects from the inner client indexed by ID
+func (c *ScopedClient) projectsByID(ctx context.Context) (map[string]Project, error) {
+	projects, err := c.inner.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Project, len(projects))
+	for _, p := range projects {
+		byID[p.ID] = p
+	}
+	return byID, nil
+}
+
+// tagScope returns the scope with the tag IDs in its tag lists resolved to
+// the tags' names, which is all a task carries
+func (c *ScopedClient) tagScope(ctx context.Context) (Scope, error) {
+	if len(c.scope.AllowTags) == 0 && len(c.scope.DenyTags) == 0 {
+		return c.scope, nil
+	}
+	tags, err := c.inner.ListTags(ctx)
+	if err != nil {
+		return Scope{}, err
+	}
+
+	s := c.scope
+	s.AllowTags = withTagNames(s.AllowTags, tags)
+	s.DenyTags = withTagNames(s.DenyTags, tags)
+	return s, nil
+}
+
+// withTagNames returns entries plus the name of every tag whose ID is one
+// of them
+func withTagNames(entries []string, tags []Tag) []string {
+	if len(entries) == 0 {
+		return entries
+	}
+	resolved := append([]string{}, entries...)
+	for _, tag := range tags {
+		if matchesAny(entries, tag.ID) {
+			resolved = append(resolved, tag.Name)
+		}
+	}
+	return resolved
+}
+
+// taskInScope resolves a task's project and checks it against scope, which
+// must have its tag IDs resolved by tagScope
+func taskInScope(scope Scope, t Task, projects map[string]Project) bool {
+	var project *Project
+	if t.ContainingProjectID != nil {
+		if p, ok := projects[*t.ContainingProjectID]; ok {
+			project = &p
+		}
+	}
+	return scope.TaskInScope(t, project)
+}
+
+// ListProjects returns the projects that are in scope
+func (c *ScopedClient) ListProjects(ctx context.Context) ([]Project, error) {
+	projects, err := c.inner.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Project, 0, len(projects))
+	for _, p := range projects {
+		if c.scope.ProjectInScope(p) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// ListTasks returns the tasks that are in scope, rejecting requests for an
+// out-of-scope project
+func (c *ScopedClient) ListTasks(ctx context.Context, projectID string) ([]Task, error) {
+	projects, err := c.projectsByID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectID != "" {
+		if p, ok := projects[projectID]; ok && !c.scope.ProjectInScope(p) {
+			return nil, fmt.Errorf("project %s is %w", projectID, ErrOutOfScope)
+		}
+	}
+
+	scope, err := c.tagScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := c.inner.ListTasks(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if taskInScope(scope, t, projects) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
+
+// ListTags returns the tags that are in scope
+func (c *ScopedClient) ListTags(ctx context.Context) ([]Tag, error) {
+	tags, err := c.inner.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Tag, 0, len(tags))
+	for _, tag := range tags {
+		if c.scope.TagInScope(tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+	return filtered, nil
+}
+
+// CreateTask creates a task if its destination and tags are in scope
+func (c *ScopedClient) CreateTask(ctx context.Context, req CreateTaskRequest) (*OperationResult, error) {
+	var project *Project
+	if req.ProjectID != "" {
+		projects, err := c.projectsByID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := projects[req.ProjectID]
+		if !ok {
+			return nil, fmt.Errorf("project %s %w", req.ProjectID, ErrNotFound)
+		}
+		project = &p
+	}
+
+	scope, err := c.tagScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.TaskInScope(Task{Name: req.Name, Tags: req.Tags}, project) {
+		return nil, fmt.Errorf("task %q is %w", req.Name, ErrOutOfScope)
+	}
+
+	return c.inner.CreateTask(ctx, req)
+}
+
+// CreateProject creates a project if its name and tags are in scope. New
+// projects are created at the top level, outside any folder.
+func (c *ScopedClient) CreateProject(ctx context.Context, req CreateProjectRequest) (*OperationResult, error) {
+	scope, err := c.tagScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.ProjectInScope(Project{Name: req.Name}) || matchesAny(scope.DenyTags, req.Tags...) {
+		return nil, fmt.Errorf("project %q is %w", req.Name, ErrOutOfScope)
+	}
+
+	return c.inner.CreateProject(ctx, req)
+}
+
+// UpdateTask updates a task if it is in scope
+func (c *ScopedClient) UpdateTask(ctx context.Context, req UpdateTaskRequest) (*OperationResult, error) {
+	if err := c.checkTask(ctx, req.ID); err != nil {
+		return nil, err
+	}
+
+	return c.inner.UpdateTask(ctx, req)
+}
+
+// CompleteTask completes a task if it is in scope
+func (c *ScopedClient) CompleteTask(ctx context.Context, taskID string) (*OperationResult, error) {
+	if err := c.checkTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	return c.inner.CompleteTask(ctx, taskID)
+}
+
+// ProjectVisible reports whether the project with the given ID exists and is
+// in scope
+func (c *ScopedClient) ProjectVisible(ctx context.Context, projectID string) bool {
+	projects, err := c.projectsByID(ctx)
+	if err != nil {
+		return false
+	}
+	p, ok := projects[projectID]
+	return ok && c.scope.ProjectInScope(p)
+}
+
+// TaskVisible reports whether the task with the given ID exists and is in
+// scope
+func (c *ScopedClient) TaskVisible(ctx context.Context, taskID string) bool {
+	return c.checkTask(ctx, taskID) == nil
+}
+
+// checkTask looks up a task by ID and returns ErrOutOfScope if it is not
+// visible under the scope. Unknown tasks are treated as out of scope so the
+// scope cannot be probed for hidden IDs.
+func (c *ScopedClient) checkTask(ctx context.Context, taskID string) error {
+	projects, err := c.projectsByID(ctx)
+	if err != nil {
+		return err
+	}
+
+	scope, err := c.tagScope(ctx)
+	if err != nil {
+		return err
+	}
+	tasks, err := c.inner.ListTasks(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if t.ID == taskID {
+			if taskInScope(scope, t, projects) {
+				return nil
+			}
+			break
+		}
+	}
+	return fmt.Errorf("task %s is %w", taskID, ErrOutOfScope)
+}
@@ -0,0 +1,259 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeClient is an in-memory OmniFocusClient used to test decorators.
+type fakeClient struct {
+	projects []Project
+	tasks    []Task
+	tags     []Tag
+
+	created   []CreateTaskRequest
+	updated   []UpdateTaskRequest
+	completed []string
+}
+
+func (f *fakeClient) ListProjects(ctx context.Context) ([]Project, error) { return f.projects, nil }
+func (f *fakeClient) ListTasks(ctx context.Context, projectID string) ([]Task, error) {
+	if projectID == "" {
+		return f.tasks, nil
+	}
+	var tasks []Task
+	for _, t := range f.tasks {
+		if t.ContainingProjectID != nil && *t.ContainingProjectID == projectID {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+func (f *fakeClient) ListTags(ctx context.Context) ([]Tag, error) { return f.tags, nil }
+func (f *fakeClient) CreateTask(ctx context.Context, req CreateTaskRequest) (*OperationResult, error) {
+	f.created = append(f.created, req)
+	return &OperationResult{ID: "new", Name: req.Name, Success: true}, nil
+}
+func (f *fakeClient) CreateProject(ctx context.Context, req CreateProjectRequest) (*OperationResult, error) {
+	return &OperationResult{ID: "new", Name: req.Name, Success: true}, nil
+}
+func (f *fakeClient) UpdateTask(ctx context.Context, req UpdateTaskRequest) (*OperationResult, error) {
+	f.updated = append(f.updated, req)
+	return &OperationResult{ID: req.ID, Success: true}, nil
+}
+func (f *fakeClient) CompleteTask(ctx context.Context, taskID string) (*OperationResult, error) {
+	f.completed = append(f.completed, taskID)
+	return &OperationResult{ID: taskID, Success: true}, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+// newScopeFixture returns a fake database with a Work and a Personal folder.
+func newScopeFixture() *fakeClient {
+	return &fakeClient{
+		projects: []Project{
+			{ID: "p-work", Name: "Launch", FolderID: strPtr("f-work"), FolderPath: []string{"Work"}},
+			{ID: "p-home", Name: "Garden", FolderID: strPtr("f-home"), FolderPath: []string{"Personal"}},
+			{ID: "p-nested", Name: "Taxes", FolderID: strPtr("f-money"), FolderPath: []string{"Personal", "Money"}},
+		},
+		tasks: []Task{
+			{ID: "t1", Name: "Write spec", ContainingProjectID: strPtr("p-work")},
+			{ID: "t2", Name: "Plant roses", ContainingProjectID: strPtr("p-home")},
+			{ID: "t3", Name: "File return", ContainingProjectID: strPtr("p-nested")},
+			{ID: "t4", Name: "Inbox errand", Tags: []string{"errands"}},
+			{ID: "t5", Name: "Secret", ContainingProjectID: strPtr("p-work"), Tags: []string{"private"}},
+		},
+		tags: []Tag{{ID: "tag1", Name: "errands"}, {ID: "tag2", Name: "private"}},
+	}
+}
+
+func taskIDs(tasks []Task) []string {
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+func TestScopeIsEmpty(t *testing.T) {
+	if !(Scope{}).IsEmpty() {
+		t.Error("zero Scope should be empty")
+	}
+	if (Scope{DenyTags: []string{"x"}}).IsEmpty() {
+		t.Error("Scope with deny list should not be empty")
+	}
+}
+
+func TestScopedClient_DenyFolderHidesNestedProjects(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{DenyFolders: []string{"personal"}})
+
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(projects) != 1 || projects[0].ID != "p-work" {
+		t.Errorf("expected only p-work, got %+v", projects)
+	}
+
+	tasks, _ := c.ListTasks(ctx, "")
+	got := taskIDs(tasks)
+	if len(got) != 3 || got[0] != "t1" || got[1] != "t4" || got[2] != "t5" {
+		t.Errorf("unexpected tasks %v", got)
+	}
+}
+
+func TestScopedClient_AllowFolderHidesInbox(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{AllowFolders: []string{"Work"}, DenyTags: []string{"private"}})
+
+	tasks, _ := c.ListTasks(ctx, "")
+	got := taskIDs(tasks)
+	if len(got) != 1 || got[0] != "t1" {
+		t.Errorf("expected only t1, got %v", got)
+	}
+
+	tags, _ := c.ListTags(ctx)
+	if len(tags) != 1 || tags[0].Name != "errands" {
+		t.Errorf("expected private tag hidden, got %+v", tags)
+	}
+}
+
+func TestScopedClient_AllowTagsAdmitsInboxTasks(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{AllowTags: []string{"errands"}})
+
+	tasks, _ := c.ListTasks(ctx, "")
+	got := taskIDs(tasks)
+	if len(got) != 1 || got[0] != "t4" {
+		t.Errorf("expected only t4, got %v", got)
+	}
+}
+
+func TestScopedClient_AllowTagsKeepsProjectsVisible(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{AllowTags: []string{"errands"}})
+
+	// Projects carry no tags, so a tag-only allow list cannot hide them
+	projects, _ := c.ListProjects(ctx)
+	if len(projects) != 3 {
+		t.Errorf("expected every project to stay visible, got %+v", projects)
+	}
+	tasks, err := c.ListTasks(ctx, "p-work")
+	if err != nil || len(tasks) != 0 {
+		t.Errorf("expected the project's untagged tasks to be hidden, got %v, %v", taskIDs(tasks), err)
+	}
+}
+
+func TestScopedClient_DenyTagByID(t *testing.T) {
+	ctx := context.Background()
+	inner := newScopeFixture()
+	c := NewScopedClient(inner, Scope{DenyTags: []string{"TAG2"}})
+
+	tags, _ := c.ListTags(ctx)
+	if len(tags) != 1 || tags[0].ID != "tag1" {
+		t.Errorf("expected the denied tag to be hidden, got %+v", tags)
+	}
+	tasks, _ := c.ListTasks(ctx, "")
+	for _, task := range tasks {
+		if task.ID == "t5" {
+			t.Error("expected the task with the denied tag to be hidden")
+		}
+	}
+	if _, err := c.CompleteTask(ctx, "t5"); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CompleteTask: expected ErrOutOfScope, got %v", err)
+	}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "x", Tags: []string{"private"}}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CreateTask with denied tag: expected ErrOutOfScope, got %v", err)
+	}
+	if len(inner.completed) != 0 || len(inner.created) != 0 {
+		t.Error("out-of-scope mutations must not reach the inner client")
+	}
+}
+
+func TestScopedClient_AllowTagByID(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{AllowTags: []string{"tag1"}})
+
+	tasks, _ := c.ListTasks(ctx, "")
+	if got := taskIDs(tasks); len(got) != 1 || got[0] != "t4" {
+		t.Errorf("expected only t4, got %v", got)
+	}
+	if _, err := c.CompleteTask(ctx, "t4"); err != nil {
+		t.Errorf("CompleteTask: expected the allowed task to be completed, got %v", err)
+	}
+}
+
+func TestScopedClient_ListTasksOutOfScopeProject(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{DenyProjects: []string{"Garden"}})
+
+	_, err := c.ListTasks(ctx, "p-home")
+	if !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("expected ErrOutOfScope, got %v", err)
+	}
+}
+
+func TestScopedClient_Visible(t *testing.T) {
+	ctx := context.Background()
+	c := NewScopedClient(newScopeFixture(), Scope{AllowFolders: []string{"Work"}, DenyTags: []string{"private"}})
+
+	for id, want := range map[string]bool{"p-work": true, "p-home": false, "p-missing": false} {
+		if got := c.ProjectVisible(ctx, id); got != want {
+			t.Errorf("ProjectVisible(%s) = %v, want %v", id, got, want)
+		}
+	}
+	for id, want := range map[string]bool{"t1": true, "t2": false, "t4": false, "t5": false, "t-missing": false} {
+		if got := c.TaskVisible(ctx, id); got != want {
+			t.Errorf("TaskVisible(%s) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestScopedClient_RejectsOutOfScopeMutations(t *testing.T) {
+	ctx := context.Background()
+	inner := newScopeFixture()
+	c := NewScopedClient(inner, Scope{DenyFolders: []string{"Personal"}, DenyTags: []string{"private"}})
+
+	if _, err := c.CompleteTask(ctx, "t2"); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CompleteTask: expected ErrOutOfScope, got %v", err)
+	}
+	if _, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "t5"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("UpdateTask: expected ErrOutOfScope, got %v", err)
+	}
+	if _, err := c.UpdateTask(ctx, UpdateTaskRequest{ID: "missing"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("UpdateTask unknown ID: expected ErrOutOfScope, got %v", err)
+	}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "x", ProjectID: "p-nested"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CreateTask: expected ErrOutOfScope, got %v", err)
+	}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "x", Tags: []string{"Private"}}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CreateTask with denied tag: expected ErrOutOfScope, got %v", err)
+	}
+	if len(inner.completed) != 0 || len(inner.updated) != 0 || len(inner.created) != 0 {
+		t.Error("out-of-scope mutations must not reach the inner client")
+	}
+}
+
+func TestScopedClient_AllowsInScopeMutations(t *testing.T) {
+	ctx := context.Background()
+	inner := newScopeFixture()
+	c := NewScopedClient(inner, Scope{AllowFolders: []string{"f-work"}})
+
+	if _, err := c.CompleteTask(ctx, "t1"); err != nil {
+		t.Errorf("CompleteTask: unexpected error %v", err)
+	}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "x", ProjectID: "p-work"}); err != nil {
+		t.Errorf("CreateTask: unexpected error %v", err)
+	}
+	if _, err := c.CreateTask(ctx, CreateTaskRequest{Name: "inbox"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CreateTask in inbox: expected ErrOutOfScope, got %v", err)
+	}
+	if _, err := c.CreateProject(ctx, CreateProjectRequest{Name: "Top level"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CreateProject: expected ErrOutOfScope, got %v", err)
+	}
+	if len(inner.completed) != 1 || len(inner.created) != 1 {
+		t.Errorf("completed=%v created=%v", inner.completed, inner.created)
+	}
+}
@@ -0,0 +1,134 @@
+package omnifocus
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// flightGroup coalesces concurrent fetches of the same cache key, so a cold
+// cache runs a list script once however many callers are waiting for it.
+// The zero value is ready to use.
+type flightGroup[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*flight[V]
+}
+
+// flight is a fetch in progress. It runs on its own context, which is
+// cancelled once every caller waiting for it has given up, so one caller
+// cancelling does not fail the others. The context carries none of the
+// callers' values; its progress goes to every caller still waiting.
+type flight[V any] struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	waiters  int
+	progress []*progressReporter
+
+	val V
+	err error
+}
+
+// Do runs fetch for key, or joins a run already in progress, and returns
+// its result. A successful result is passed to store unless the key was
+// forgotten while fetching. If ctx is done first, Do returns ctx's error
+// without waiting.
+func (g *flightGroup[V]) Do(ctx context.Context, key string, fetch func(context.Context) (V, error), store func(V)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight[V])
+	}
+	progress := progressFrom(ctx)
+	f, ok := g.calls[key]
+	if ok {
+		f.waiters++
+	} else {
+		f = &flight[V]{done: make(chan struct{}), waiters: 1}
+		flightCtx := WithProgress(context.Background(), func(_ int, message string) {
+			g.reportProgress(f, message)
+		})
+		flightCtx, f.cancel = context.WithCancel(flightCtx)
+		g.calls[key] = f
+		go g.run(flightCtx, key, f, fetch, store)
+	}
+	if progress != nil {
+		f.progress = append(f.progress, progress)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		if g.leave(key, f, progress) {
+			// Nobody else wants the result; wait for the script to stop so
+			// it does not outlive the call that started it
+			<-f.done
+		}
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// run executes fetch and publishes its result to the flight's waiters
+func (g *flightGroup[V]) run(ctx context.Context, key string, f *flight[V], fetch func(context.Context) (V, error), store func(V)) {
+	f.val, f.err = fetch(ctx)
+	f.cancel()
+
+	g.mu.Lock()
+	if g.calls[key] == f {
+		delete(g.calls, key)
+		if f.err == nil && store != nil {
+			store(f.val)
+		}
+	}
+	g.mu.Unlock()
+	close(f.done)
+}
+
+// reportProgress sends a progress update of f's fetch to its waiters
+func (g *flightGroup[V]) reportProgress(f *flight[V], message string) {
+	g.mu.Lock()
+	waiting := slices.Clone(f.progress)
+	g.mu.Unlock()
+
+	for _, p := range waiting {
+		p.report(message)
+	}
+}
+
+// leave records that a waiter gave up, cancelling the flight and reporting
+// true if it was the last one
+func (g *flightGroup[V]) leave(key string, f *flight[V], progress *progressReporter) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if i := slices.Index(f.progress, progress); progress != nil && i >= 0 {
+		f.progress = slices.Delete(f.progress, i, i+1)
+	}
+	f.waiters--
+	if f.waiters == 0 {
+		// Later callers must not join a cancelled fetch
+		if g.calls[key] == f {
+			delete(g.calls, key)
+		}
+		f.cancel()
+		return true
+	}
+	return false
+}
+
+// ForgetPrefix stops callers from joining in-progress fetches of keys with
+// the given prefix, and stops those fetches from storing their results. It
+// is called before those keys are invalidated, since a fetch that started
+// before a write may not reflect it.
+func (g *flightGroup[V]) ForgetPrefix(prefix string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for key := range g.calls {
+		if strings.HasPrefix(key, prefix) {
+			delete(g.calls, key)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package omnifocus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedExecutor blocks every script run until release is closed, counting
+// the runs and reporting each start on started.
+type gatedExecutor struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	output  []byte
+	err     error
+}
+
+func newGatedExecutor(output []byte, err error) *gatedExecutor {
+	return &gatedExecutor{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}),
+		output:  output,
+		err:     err,
+	}
+}
+
+func (g *gatedExecutor) run(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+	g.calls.Add(1)
+	g.started <- struct{}{}
+	select {
+	case <-g.release:
+		return g.output, g.err
+	case <-ctx.Done():
+		return nil, errors.New("signal: killed")
+	}
+}
+
+// listConcurrently calls ListTasks n times in parallel once the first run
+// has started and returns the results once the executor is released.
+func listConcurrently(t *testing.T, c *Client, exec *gatedExecutor, n int) ([][]Task, []error) {
+	t.Helper()
+	results := make([][]Task, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = c.ListTasks(context.Background(), "")
+		}(i)
+	}
+	<-exec.started
+	// Give the other callers time to join the running fetch
+	time.Sleep(20 * time.Millisecond)
+	close(exec.release)
+	wg.Wait()
+	return results, errs
+}
+
+func TestListTasks_ConcurrentMissesShareOneRun(t *testing.T) {
+	exec := newGatedExecutor(mustJSON([]Task{{ID: "t1"}}), nil)
+	c := NewClientWithCache("/fake/scripts", 30*time.Second)
+	c.executor = exec.run
+
+	results, errs := listConcurrently(t, c, exec, 5)
+
+	if n := exec.calls.Load(); n != 1 {
+		t.Errorf("expected 1 script run, got %d", n)
+	}
+	for i := range results {
+		if errs[i] != nil || len(results[i]) != 1 || results[i][0].ID != "t1" {
+			t.Errorf("caller %d: tasks=%+v err=%v", i, results[i], errs[i])
+		}
+	}
+}
+
+func TestListTasks_ConcurrentMissesShareError(t *testing.T) {
+	exec := newGatedExecutor(mustJSON(map[string]string{"error": "Project not found", "code": "NOT_FOUND"}), nil)
+	c := NewClientWithCache("/fake/scripts", 30*time.Second)
+	c.executor = exec.run
+
+	_, errs := listConcurrently(t, c, exec, 3)
+
+	if n := exec.calls.Load(); n != 1 {
+		t.Errorf("expected 1 script run, got %d", n)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("caller %d: expected ErrNotFound, got %v", i, err)
+		}
+	}
+}
+
+func TestListTasks_CancelledWaiterDoesNotFailOthers(t *testing.T) {
+	exec := newGatedExecutor(mustJSON([]Task{{ID: "t1"}}), nil)
+	c := NewClientWithCache("/fake/scripts", 30*time.Second)
+	c.executor = exec.run
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := c.ListTasks(ctx, "")
+		errc <- err
+	}()
+	<-exec.started
+
+	done := make(chan []Task)
+	go func() {
+		tasks, _ := c.ListTasks(context.Background(), "")
+		done <- tasks
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for the first caller, got %v", err)
+	}
+
+	close(exec.release)
+	if tasks := <-done; len(tasks) != 1 {
+		t.Errorf("second caller should get the shared result, got %+v", tasks)
+	}
+	if n := exec.calls.Load(); n != 1 {
+		t.Errorf("expected 1 script run, got %d", n)
+	}
+}
+
+func TestListTasks_InvalidationStartsFreshFetch(t *testing.T) {
+	exec := newGatedExecutor(mustJSON([]Task{{ID: "stale"}}), nil)
+	c := NewClientWithCache("/fake/scripts", 30*time.Second)
+	c.executor = exec.run
+
+	done := make(chan struct{})
+	go func() {
+		c.ListTasks(context.Background(), "")
+		close(done)
+	}()
+	<-exec.started
+
+	// A write lands while the list is running
+	c.taskCache.InvalidatePattern("tasks:")
+	close(exec.release)
+	<-done
+
+	if _, found := c.taskCache.Get("tasks:all"); found {
+		t.Error("a fetch forgotten by invalidation must not be cached")
+	}
+	c.ListTasks(context.Background(), "")
+	if n := exec.calls.Load(); n != 2 {
+		t.Errorf("expected a fresh run after invalidation, got %d runs", n)
+	}
+}
+
+func TestListTasks_SharedFetchReportsProgressToWaiters(t *testing.T) {
+	exec := newGatedExecutor(mustJSON([]Task{{ID: "t1"}}), nil)
+	c := NewClientWithCache("/fake/scripts", 30*time.Second)
+	c.executor = exec.run
+
+	first, firstMessages := recordProgress(t)
+	first, cancel := context.WithCancel(first)
+	errc := make(chan error)
+	go func() {
+		_, err := c.ListTasks(first, "")
+		errc <- err
+	}()
+	<-exec.started
+
+	second, secondMessages := recordProgress(t)
+	done := make(chan error)
+	go func() {
+		_, err := c.ListTasks(second, "")
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The caller that started the fetch leaves; the one still waiting gets
+	// the rest of its progress
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for the first caller, got %v", err)
+	}
+	close(exec.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*firstMessages) != 1 {
+		t.Errorf("expected only the launch for the first caller, got %q", *firstMessages)
+	}
+	if len(*secondMessages) != 1 || !strings.HasPrefix((*secondMessages)[0], "Parsing tasks") {
+		t.Errorf("expected the parsing phase for the second caller, got %q", *secondMessages)
+	}
+}
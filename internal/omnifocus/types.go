@@ -1,27 +1,47 @@
 package omnifocus
 
+import "slices"
+
 // Project represents an OmniFocus project
 type Project struct {
-	ID                      string `json:"id"`
-	Name                    string `json:"name"`
-	Status                  string `json:"status"`
-	Note                    string `json:"note"`
-	Completed               bool   `json:"completed"`
-	NumberOfTasks           int    `json:"numberOfTasks"`
-	NumberOfCompletedTasks  int    `json:"numberOfCompletedTasks"`
+	ID                     string   `json:"id"`
+	Name                   string   `json:"name"`
+	Status                 string   `json:"status"`
+	Note                   string   `json:"note"`
+	Completed              bool     `json:"completed"`
+	NumberOfTasks          int      `json:"numberOfTasks"`
+	NumberOfCompletedTasks int      `json:"numberOfCompletedTasks"`
+	FolderID               *string  `json:"folderId"`
+	FolderPath             []string `json:"folderPath"`
 }
 
 // Task represents an OmniFocus task
 type Task struct {
-	ID                   string    `json:"id"`
-	Name                 string    `json:"name"`
-	Note                 string    `json:"note"`
-	Completed            bool      `json:"completed"`
-	Flagged              bool      `json:"flagged"`
-	DueDate              *string   `json:"dueDate"`
-	EstimatedMinutes     *int      `json:"estimatedMinutes"`
-	Tags                 []string  `json:"tags"`
-	ContainingProjectID  *string   `json:"containingProjectId"`
+	ID                  string   `json:"id"`
+	Name                string   `json:"name"`
+	Note                string   `json:"note"`
+	Completed           bool     `json:"completed"`
+	Flagged             bool     `json:"flagged"`
+	DueDate             *string  `json:"dueDate"`
+	EstimatedMinutes    *int     `json:"estimatedMinutes"`
+	Tags                []string `json:"tags"`
+	ContainingProjectID *string  `json:"containingProjectId"`
+}
+
+// Clone returns a deep copy of the project
+func (p Project) Clone() Project {
+	p.FolderID = clonePtr(p.FolderID)
+	p.FolderPath = slices.Clone(p.FolderPath)
+	return p
+}
+
+// Clone returns a deep copy of the task
+func (t Task) Clone() Task {
+	t.DueDate = clonePtr(t.DueDate)
+	t.EstimatedMinutes = clonePtr(t.EstimatedMinutes)
+	t.Tags = slices.Clone(t.Tags)
+	t.ContainingProjectID = clonePtr(t.ContainingProjectID)
+	return t
 }
 
 // Tag represents an OmniFocus tag
@@ -31,6 +51,11 @@ type Tag struct {
 	Available bool   `json:"available"`
 }
 
+// Clone returns a copy of the tag
+func (t Tag) Clone() Tag {
+	return t
+}
+
 // CreateTaskRequest represents the data needed to create a task
 type CreateTaskRequest struct {
 	Name             string   `json:"name"`
@@ -59,12 +84,62 @@ type UpdateTaskRequest struct {
 	Flagged          *bool   `json:"flagged,omitempty"`
 	DueDate          *string `json:"dueDate,omitempty"`
 	EstimatedMinutes *int    `json:"estimatedMinutes,omitempty"`
+	// ClearDueDate removes the task's due date; DueDate is then ignored
+	ClearDueDate bool `json:"clearDueDate,omitempty"`
+}
+
+// DatabaseState holds cheap markers of the state of the OmniFocus database,
+// used to detect changes made outside the server
+type DatabaseState struct {
+	// Running is false when OmniFocus is not open; the markers are then
+	// empty
+	Running  bool         `json:"running"`
+	Projects ChangeMarker `json:"projects"`
+	Tasks    ChangeMarker `json:"tasks"`
+	Tags     ChangeMarker `json:"tags"`
+}
+
+// ChangeMarker summarises one kind of item. Modified is the latest
+// modification date, where OmniFocus records one. Fingerprint is a hash of
+// every modification date, which changes even when an edit carries an
+// older date than the latest, or of the items' names where there are no
+// dates.
+type ChangeMarker struct {
+	Count       int     `json:"count"`
+	Modified    *string `json:"modified"`
+	Fingerprint string  `json:"fingerprint,omitempty"`
 }
 
 // OperationResult represents the result of a create/update operation
 type OperationResult struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	Code    ErrorCode `json:"code,omitempty"`
+	// Task and Project are the affected items as they are after the
+	// operation, when the script reports them
+	Task    *Task    `json:"task,omitempty"`
+	Project *Project `json:"project,omitempty"`
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil if p is nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// cloneAll deep-copies a slice of cloneable items, preserving nil
+func cloneAll[T interface{ Clone() T }](items []T) []T {
+	if items == nil {
+		return nil
+	}
+	out := make([]T, len(items))
+	for i, item := range items {
+		out[i] = item.Clone()
+	}
+	return out
 }